@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// buildRunner runs the configured build command whenever the sources change, cancelling a
+// build that is still running when they change again
+type buildRunner struct {
+	command string
+	sources *changeNotifier
+
+	// Finished receives the result of every build that ran to completion
+	Finished chan bool
+
+	mu        sync.Mutex
+	running   bool
+	succeeded bool
+}
+
+// newBuildRunner prepares a runner for config.BuildCommand, watching config.BuildSources
+func newBuildRunner() *buildRunner {
+	return &buildRunner{
+		command:   config.BuildCommand,
+		Finished:  make(chan bool, 1),
+		succeeded: true,
+	}
+}
+
+// ready reports whether built jars can be deployed: no build is running and the last one
+// did not fail
+func (b *buildRunner) ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.running && b.succeeded
+}
+
+// Start runs the build once and then again on every source change, until stop is closed
+func (b *buildRunner) Start(stop <-chan struct{}) {
+	var sources []WatchPath
+	for _, source := range config.BuildSources {
+		if info, err := os.Stat(source); err != nil || !info.IsDir() {
+			log.Printf("Warning: build source directory %s not found, its changes will not trigger builds", source)
+			continue
+		}
+		sources = append(sources, WatchPath{Path: source, Recursive: true})
+	}
+	b.sources = newChangeNotifier(sources, func(string) bool { return true })
+	b.setRunning(true)
+
+	go func() {
+		defer b.sources.Close()
+
+		var cancel context.CancelFunc
+		var done chan struct{}
+		start := func() {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			done = make(chan struct{})
+			b.setRunning(true)
+			go b.run(ctx, done)
+		}
+		stopBuild := func() {
+			if cancel != nil {
+				cancel()
+				<-done
+				cancel = nil
+			}
+		}
+
+		start()
+		for {
+			select {
+			case <-b.sources.C:
+				if cancel != nil {
+					select {
+					case <-done:
+					default:
+						printWithPrompt("[Golem] Sources changed, cancelling the running build")
+					}
+				}
+				stopBuild()
+				start()
+			case <-stop:
+				stopBuild()
+				return
+			}
+		}
+	}()
+}
+
+// run runs the build command once, streaming its output, and reports the result unless the
+// build was cancelled
+func (b *buildRunner) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	printWithPrompt(fmt.Sprintf("[Golem] Building: %s", b.command))
+	started := time.Now()
+
+	cmd := buildShellCommand(b.command)
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		b.finish(ctx, false, fmt.Errorf("failed to create output pipe: %v", err), started)
+		return
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Start(); err != nil {
+		b.finish(ctx, false, fmt.Errorf("failed to start build: %v", err), started)
+		return
+	}
+
+	// exec.CommandContext would only kill the shell, leaving Gradle or Maven running
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killBuildProcess(cmd)
+		case <-exited:
+		}
+	}()
+
+	streamBuildOutput(output)
+	err = cmd.Wait()
+	close(exited)
+	b.finish(ctx, err == nil, err, started)
+}
+
+// finish records the result of a build and reports it on Finished
+func (b *buildRunner) finish(ctx context.Context, ok bool, err error, started time.Time) {
+	if ctx.Err() != nil {
+		// Cancelled builds are replaced by a new one, which reports instead
+		return
+	}
+
+	elapsed := time.Since(started).Round(100 * time.Millisecond)
+	if ok {
+		printWithPrompt(fmt.Sprintf("[Golem] Build succeeded in %s", elapsed))
+	} else {
+		printWithPrompt(fmt.Sprintf("[Golem] Build failed after %s (%v), plugins will not be deployed until it succeeds", elapsed, err))
+	}
+
+	b.mu.Lock()
+	b.running = false
+	b.succeeded = ok
+	b.mu.Unlock()
+
+	// Only the latest result matters
+	select {
+	case <-b.Finished:
+	default:
+	}
+	b.Finished <- ok
+}
+
+func (b *buildRunner) setRunning(running bool) {
+	b.mu.Lock()
+	b.running = running
+	b.mu.Unlock()
+}
+
+// buildShellCommand runs a build command line through the platform's shell, so it can use
+// pipes, && and the like
+func buildShellCommand(command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	prepareBuildProcess(cmd)
+	return cmd
+}
+
+// streamBuildOutput prints each line of build output with a [Build] prefix
+func streamBuildOutput(output io.Reader) {
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 4096), 256*1024)
+	for scanner.Scan() {
+		printWithPrompt("[Build] " + strings.TrimRight(scanner.Text(), "\r"))
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitForBuild returns the next build result, failing the test if none arrives in time
+func waitForBuild(t *testing.T, builder *buildRunner, timeout time.Duration) bool {
+	t.Helper()
+	select {
+	case ok := <-builder.Finished:
+		return ok
+	case <-time.After(timeout):
+		t.Fatalf("No build result within %s", timeout)
+		return false
+	}
+}
+
+func TestBuildRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh commands")
+	}
+
+	origConfig := config
+	defer func() { config = origConfig }()
+
+	sources := t.TempDir()
+	config.BuildSources = []string{sources}
+
+	t.Run("reports success and failure", func(t *testing.T) {
+		for command, want := range map[string]bool{"echo compiled": true, "echo broken >&2; exit 1": false} {
+			config.BuildCommand = command
+			builder := newBuildRunner()
+			stop := make(chan struct{})
+			builder.Start(stop)
+
+			if got := waitForBuild(t, builder, 10*time.Second); got != want {
+				t.Errorf("%q: expected success %v, got %v", command, want, got)
+			}
+			if builder.ready() != want {
+				t.Errorf("%q: ready should follow the build result", command)
+			}
+			close(stop)
+		}
+	})
+
+	t.Run("cancels a running build when sources change", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "builds")
+		// The first build hangs; the one started after the source change finishes quickly
+		config.BuildCommand = "echo run >> " + marker + "; if [ $(wc -l < " + marker + ") -eq 1 ]; then sleep 60; fi"
+		builder := newBuildRunner()
+		stop := make(chan struct{})
+		defer close(stop)
+		builder.Start(stop)
+
+		time.Sleep(500 * time.Millisecond)
+		if builder.ready() {
+			t.Fatalf("Jars should not be deployed while a build is running")
+		}
+		if err := os.WriteFile(filepath.Join(sources, "Main.java"), []byte("class Main {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if !waitForBuild(t, builder, 20*time.Second) {
+			t.Errorf("Expected the second build to succeed")
+		}
+	})
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// prepareBuildProcess needs nothing on Windows, where taskkill follows the process tree
+func prepareBuildProcess(cmd *exec.Cmd) {}
+
+// killBuildProcess kills the build and every process it started
+func killBuildProcess(cmd *exec.Cmd) {
+	exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
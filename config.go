@@ -1,28 +1,623 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFormat identifies the syntax a config file is written in
+type configFormat string
+
+const (
+	formatJSON configFormat = "json"
+	formatYAML configFormat = "yaml"
+	formatTOML configFormat = "toml"
 )
 
+// defaultConfigFiles are the config files looked up when --config is omitted, in order of preference
+var defaultConfigFiles = []string{
+	"golem-config.json",
+	"golem-config.yaml",
+	"golem-config.yml",
+	"golem-config.toml",
+}
+
+var ramPattern = regexp.MustCompile(`^[0-9]+[KkMmGgTt]?$`)
+
+// configFormatFor detects the config format from the file extension
+func configFormatFor(path string) (configFormat, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return formatJSON, nil
+	case ".yaml", ".yml":
+		return formatYAML, nil
+	case ".toml":
+		return formatTOML, nil
+	default:
+		return "", fmt.Errorf("unsupported config file extension %q (expected .json, .yaml, .yml or .toml)", ext)
+	}
+}
+
+// findConfigFile returns the first default config file present in the working directory
+func findConfigFile() string {
+	var found []string
+	for _, name := range defaultConfigFiles {
+		if _, err := os.Stat(name); err == nil {
+			found = append(found, name)
+		}
+	}
+
+	if len(found) == 0 {
+		return defaultConfigFiles[0]
+	}
+	if len(found) > 1 {
+		log.Printf("Multiple config files found (%s), using %s", strings.Join(found, ", "), found[0])
+	}
+	return found[0]
+}
+
+// loadConfig builds the effective config from the config file, GOLEM_* environment variables
+// and command-line flags, in increasing order of precedence, with defaults for anything unset
 func loadConfig(path string) error {
-	data, err := os.ReadFile(path)
+	config = Config{}
+	configSources = map[string]string{}
+
+	doc, err := readConfigDocument(path)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %v", err)
+		return err
+	}
+
+	profiles, defaultProfile, err := extractProfiles(doc)
+	if err != nil {
+		return err
 	}
 
-	if err := json.Unmarshal(data, &config); err != nil {
+	if err := decodeConfigDocument(doc, &config); err != nil {
 		return fmt.Errorf("failed to parse config file: %v", err)
 	}
+	for key := range doc {
+		configSources[key] = sourceFile
+	}
+
+	// Layer the selected profile over the shared values
+	activeProfile = args.Profile
+	if activeProfile == "" {
+		activeProfile = defaultProfile
+	}
+	if activeProfile == "" && len(profiles) > 0 {
+		return fmt.Errorf("config defines profiles (%s); select one with --profile or set defaultProfile",
+			strings.Join(sortedKeys(profiles), ", "))
+	}
+	if activeProfile != "" {
+		if err := applyProfile(&config, profiles, activeProfile); err != nil {
+			return err
+		}
+	}
+
+	if err := applyEnvOverrides(&config); err != nil {
+		return err
+	}
+	if err := applyFlagOverrides(&config, &args); err != nil {
+		return err
+	}
+	applyConfigDefaults(&config)
+
+	return validateConfig(&config)
+}
+
+// extractProfiles removes the profiles section and default profile name from a config
+// document, leaving only the shared values
+func extractProfiles(doc map[string]interface{}) (map[string]map[string]interface{}, string, error) {
+	profiles := map[string]map[string]interface{}{}
+	if raw, ok := doc["profiles"]; ok {
+		section, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("profiles must be a mapping of profile names to settings")
+		}
+		for name, value := range section {
+			overrides, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, "", fmt.Errorf("profile %q must be a mapping of settings", name)
+			}
+			if _, nested := overrides["profiles"]; nested {
+				return nil, "", fmt.Errorf("profile %q cannot define its own profiles", name)
+			}
+			profiles[name] = overrides
+		}
+		delete(doc, "profiles")
+	}
+
+	defaultProfile := ""
+	if raw, ok := doc["defaultProfile"]; ok {
+		name, ok := raw.(string)
+		if !ok {
+			return nil, "", fmt.Errorf("defaultProfile must be a profile name")
+		}
+		defaultProfile = name
+		delete(doc, "defaultProfile")
+	}
+
+	return profiles, defaultProfile, checkProfileServerPaths(doc, profiles)
+}
+
+// applyProfile layers the overrides of the named profile onto c. A profile that does not set
+// its own serverPath gets a subdirectory of the shared one, so profiles never share a server.
+func applyProfile(c *Config, profiles map[string]map[string]interface{}, name string) error {
+	overrides, ok := profiles[name]
+	if !ok {
+		available := "none defined"
+		if len(profiles) > 0 {
+			available = "available: " + strings.Join(sortedKeys(profiles), ", ")
+		}
+		return fmt.Errorf("unknown profile %q (%s)", name, available)
+	}
+
+	if err := decodeConfigDocument(overrides, c); err != nil {
+		return fmt.Errorf("failed to parse profile %q: %v", name, err)
+	}
+	for key := range overrides {
+		configSources[key] = sourceProfile
+	}
 
+	if _, ok := overrides["serverPath"]; !ok {
+		c.ServerPath = profileServerPath(c.ServerPath, name)
+		configSources["serverPath"] = sourceProfile
+	}
 	return nil
 }
 
-func saveConfig(path string) error {
-	data, err := json.MarshalIndent(config, "", "    ")
+// profileServerPath returns the server directory used by a profile without its own serverPath
+func profileServerPath(shared, name string) string {
+	if shared == "" {
+		shared = defaultConfig.ServerPath
+	}
+	return filepath.Join(shared, name)
+}
+
+// checkProfileServerPaths rejects profiles that would run from the same server directory
+func checkProfileServerPaths(shared map[string]interface{}, profiles map[string]map[string]interface{}) error {
+	sharedPath, _ := shared["serverPath"].(string)
+
+	owners := make(map[string]string)
+	for _, name := range sortedKeys(profiles) {
+		path, ok := profiles[name]["serverPath"].(string)
+		if !ok {
+			path = profileServerPath(sharedPath, name)
+		}
+		path = filepath.Clean(path)
+		if other, taken := owners[path]; taken {
+			return fmt.Errorf("profiles %q and %q both use serverPath %s", other, name, path)
+		}
+		owners[path] = name
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// saveBuildNumber records the installed build in the config file, under the active
+// profile when there is one
+func saveBuildNumber(build int) error {
+	if activeProfile != "" {
+		return setConfigValue(args.Config, build, "profiles", activeProfile, "buildNumber")
+	}
+	return setConfigValue(args.Config, build, "buildNumber")
+}
+
+// readConfigDocument parses a config file of any supported format into a generic document
+func readConfigDocument(path string) (map[string]interface{}, error) {
+	format, err := configFormatFor(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	doc := map[string]interface{}{}
+	switch format {
+	case formatJSON:
+		err = json.Unmarshal(data, &doc)
+	case formatYAML:
+		err = yaml.Unmarshal(data, &doc)
+	case formatTOML:
+		err = toml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %v", err)
+	}
+
+	return doc, nil
+}
+
+// decodeConfigDocument maps a generic document onto target through the Config json tags,
+// so every file format shares the same field names
+func decodeConfigDocument(doc map[string]interface{}, target *Config) error {
+	data, err := json.Marshal(doc)
 	if err != nil {
 		return err
 	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			return fmt.Errorf("%s must be a %s, got %s (quote the value if it is a version number)",
+				typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// validateConfig checks a loaded config for values that would only fail later at runtime
+func validateConfig(c *Config) error {
+	switch c.ServerType {
+	case Paper, Folia, Purpur, Vanilla:
+	default:
+		return fmt.Errorf("unknown server type: %q", c.ServerType)
+	}
+
+	if c.BuildNumber < 0 {
+		return fmt.Errorf("buildNumber must not be negative")
+	}
+	if c.ServerPath == "" {
+		return fmt.Errorf("serverPath must be set")
+	}
+
+	if _, ok := jvmPresets[c.JVMPreset]; c.JVMPreset != "" && !ok {
+		return fmt.Errorf("unknown jvmPreset %q (available: %s)", c.JVMPreset, strings.Join(jvmPresetNames(), ", "))
+	}
+
+	for i, p := range c.WatchPaths {
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("watchPaths[%d]: %v", i, err)
+		}
+	}
+
+	if err := validateDeployMode(c.DeployMode); err != nil {
+		return err
+	}
+
+	durations := map[string]string{"watchQuietPeriod": c.WatchQuietPeriod, "watchDebounce": c.WatchDebounce, "readyTimeout": c.ReadyTimeout}
+	for name, value := range durations {
+		if d, err := time.ParseDuration(value); value != "" && (err != nil || d < 0) {
+			return fmt.Errorf("%s must be a duration like 500ms or 2s, got %q", name, value)
+		}
+	}
+
+	if _, err := compileReadyPatterns(c.ReadyPatterns); err != nil {
+		return err
+	}
+
+	if err := validateReloadSettings("reloadStrategy", PluginReload{Strategy: c.ReloadStrategy, Commands: c.ReloadCommands}); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(c.PluginReload) {
+		settings := c.PluginReload[name]
+		if settings.Strategy == "" {
+			settings.Strategy = c.ReloadStrategy
+		}
+		if len(settings.Commands) == 0 {
+			settings.Commands = c.ReloadCommands
+		}
+		if err := validateReloadSettings("pluginReload."+name, settings); err != nil {
+			return err
+		}
+	}
+
+	for name, value := range map[string]string{"minRam": c.MinRAM, "maxRam": c.MaxRAM} {
+		if value != "" && !ramPattern.MatchString(value) {
+			return fmt.Errorf("%s must be a memory size like 1024M or 4G, got %q", name, value)
+		}
+	}
+
+	return nil
+}
+
+// writeConfigFile writes c to a new config file, in the format given by the file extension
+func writeConfigFile(path string, c *Config) error {
+	format, err := configFormatFor(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case formatYAML:
+		// Go through a node tree so keys keep the Config field order
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		setBlockStyle(&doc)
+
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&doc); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	case formatTOML:
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+
+		// Plain keys have to come before any tables, so write them first
+		var keys, tables []byte
+		root := doc.Content[0]
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			var value interface{}
+			if err := root.Content[i+1].Decode(&value); err != nil {
+				return err
+			}
+			rendered, err := toml.Marshal(map[string]interface{}{root.Content[i].Value: value})
+			if err != nil {
+				return err
+			}
+			if root.Content[i+1].Kind == yaml.MappingNode {
+				tables = append(append(tables, '\n'), rendered...)
+			} else {
+				keys = append(keys, rendered...)
+			}
+		}
+		data = append(keys, tables...)
+	default:
+		data = append(data, '\n')
+	}
+
 	return os.WriteFile(path, data, 0644)
 }
+
+// setBlockStyle switches a node tree parsed from JSON to block style YAML
+func setBlockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, child := range node.Content {
+		setBlockStyle(child)
+	}
+}
+
+// setConfigValue updates a single key in the config file at path, leaving the rest of the
+// file (key order, and comments for YAML and TOML) as it was
+func setConfigValue(path string, value interface{}, keys ...string) error {
+	format, err := configFormatFor(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var out []byte
+	switch format {
+	case formatJSON, formatYAML:
+		// JSON is valid YAML, so both are edited through a yaml.Node tree which keeps ordering
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file: %v", err)
+		}
+		if doc.Kind == 0 {
+			doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+		}
+		if err := setNodeValue(doc.Content[0], value, keys); err != nil {
+			return err
+		}
+
+		if format == formatJSON {
+			var buf bytes.Buffer
+			writeNodeJSON(&buf, doc.Content[0], "")
+			if bytes.HasSuffix(data, []byte("\n")) {
+				buf.WriteString("\n")
+			}
+			out = buf.Bytes()
+		} else {
+			var buf bytes.Buffer
+			encoder := yaml.NewEncoder(&buf)
+			encoder.SetIndent(2)
+			if err := encoder.Encode(&doc); err != nil {
+				return fmt.Errorf("failed to encode config file: %v", err)
+			}
+			out = buf.Bytes()
+		}
+	case formatTOML:
+		if out, err = setTOMLValue(data, value, keys); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, out, 0644)
+}
+
+// setNodeValue sets the value at the key path below a YAML mapping node, creating
+// intermediate mappings when needed
+func setNodeValue(node *yaml.Node, value interface{}, keys []string) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("cannot set %s: parent is not a mapping", strings.Join(keys, "."))
+	}
+
+	key := keys[0]
+	var existing *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			existing = node.Content[i+1]
+			break
+		}
+	}
+
+	if len(keys) > 1 {
+		if existing == nil {
+			existing = &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, existing)
+		}
+		return setNodeValue(existing, value, keys[1:])
+	}
+
+	replacement := &yaml.Node{}
+	if err := replacement.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode %s: %v", key, err)
+	}
+
+	if existing == nil {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, replacement)
+		return nil
+	}
+
+	// Keep any comments attached to the old value
+	replacement.HeadComment = existing.HeadComment
+	replacement.LineComment = existing.LineComment
+	replacement.FootComment = existing.FootComment
+	*existing = *replacement
+	return nil
+}
+
+// writeNodeJSON renders a YAML node tree as indented JSON, matching json.MarshalIndent output
+func writeNodeJSON(buf *bytes.Buffer, node *yaml.Node, indent string) {
+	inner := indent + "    "
+	switch node.Kind {
+	case yaml.DocumentNode:
+		writeNodeJSON(buf, node.Content[0], indent)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, _ := json.Marshal(node.Content[i].Value)
+			buf.WriteString(inner)
+			buf.Write(key)
+			buf.WriteString(": ")
+			writeNodeJSON(buf, node.Content[i+1], inner)
+			if i+2 < len(node.Content) {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteString("[\n")
+		for i, item := range node.Content {
+			buf.WriteString(inner)
+			writeNodeJSON(buf, item, inner)
+			if i+1 < len(node.Content) {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + "]")
+	case yaml.AliasNode:
+		writeNodeJSON(buf, node.Alias, indent)
+	default:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			buf.WriteString(node.Value)
+		case "!!null":
+			buf.WriteString("null")
+		default:
+			value, _ := json.Marshal(node.Value)
+			buf.Write(value)
+		}
+	}
+}
+
+var tomlHeaderPattern = regexp.MustCompile(`^\s*\[\s*([^\[\]]+?)\s*\]\s*(#.*)?$`)
+
+// setTOMLValue rewrites or inserts a single "key = value" line in TOML source, keeping
+// comments and layout of every other line
+func setTOMLValue(data []byte, value interface{}, keys []string) ([]byte, error) {
+	table := strings.Join(keys[:len(keys)-1], ".")
+	key := keys[len(keys)-1]
+
+	rendered, err := toml.Marshal(map[string]interface{}{key: value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %v", key, err)
+	}
+	assignment := strings.TrimSpace(string(rendered))
+
+	keyPattern := regexp.MustCompile(`^(\s*)("?` + regexp.QuoteMeta(key) + `"?)\s*=\s*("[^"]*"|'[^']*'|[^#]*?)\s*(#.*)?$`)
+
+	lines := strings.Split(string(data), "\n")
+	current := ""
+	tableFound := table == ""
+	insertAt := -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			if current == table && insertAt < 0 {
+				insertAt = i
+			}
+			if match := tomlHeaderPattern.FindStringSubmatch(line); match != nil && !strings.HasPrefix(trimmed, "[[") {
+				current = strings.ReplaceAll(match[1], " ", "")
+			} else {
+				current = "\x00" // array table, never matches
+			}
+			if current == table {
+				tableFound = true
+				insertAt = -1
+			}
+			continue
+		}
+
+		if current != table {
+			continue
+		}
+		if match := keyPattern.FindStringSubmatch(line); match != nil {
+			replaced := match[1] + assignment
+			if match[4] != "" {
+				replaced += " " + match[4]
+			}
+			lines[i] = replaced
+			return []byte(strings.Join(lines, "\n")), nil
+		}
+	}
+
+	// The key is not present yet, so add it to the end of its table
+	if !tableFound {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, "", "["+table+"]", assignment, "")
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+	if insertAt < 0 {
+		insertAt = len(lines)
+	}
+	// Skip back over blank lines so the new key sits with the rest of the table
+	for insertAt > 0 && strings.TrimSpace(lines[insertAt-1]) == "" {
+		insertAt--
+	}
+	lines = append(lines[:insertAt], append([]string{assignment}, lines[insertAt:]...)...)
+	return []byte(strings.Join(lines, "\n")), nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// Sources a config value can come from, in increasing order of precedence
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceProfile = "profile"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// defaultConfig holds the values used for options that are not set anywhere else
+var defaultConfig = Config{
+	ServerType:       Paper,
+	JavaPath:         "java",
+	MinRAM:           "1G",
+	MaxRAM:           "4G",
+	ServerPath:       "./server",
+	AdoptiumURL:      "https://api.adoptium.net",
+	DeployMode:       deployCopy,
+	WatchQuietPeriod: "500ms",
+	WatchDebounce:    "1s",
+	ReloadStrategy:   reloadRestart,
+	BuildSources:     []string{"src"},
+	ReadyPatterns:    []string{`Done \([0-9.,]+s\)!`},
+	ReadyTimeout:     "5m",
+}
+
+// activeProfile is the name of the profile the effective config was built from, if any
+var activeProfile string
+
+// configSources records where each effective config value came from, keyed by file key
+var configSources = map[string]string{}
+
+// configField describes how a Config field is named in files, the environment and on the command line
+type configField struct {
+	Index int    // Field index in Config
+	Name  string // Go field name, shared with the matching Args flag
+	Key   string // Key in the config file
+	Env   string // Environment variable overriding the field
+	Flag  string // Command-line flag overriding the field
+}
+
+// configFields returns the overridable Config fields in declaration order
+func configFields() []configField {
+	var fields []configField
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		words := splitCamelCase(key)
+		fields = append(fields, configField{
+			Index: i,
+			Name:  t.Field(i).Name,
+			Key:   key,
+			Env:   "GOLEM_" + strings.ToUpper(strings.Join(words, "_")),
+			Flag:  "--" + strings.ToLower(strings.Join(words, "-")),
+		})
+	}
+	return fields
+}
+
+// splitCamelCase splits a key like "allowExperimentalBuilds" into its words
+func splitCamelCase(s string) []string {
+	var words []string
+	start := 0
+	runes := []rune(s)
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1]) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
+
+// applyEnvOverrides sets config fields from their GOLEM_* environment variables
+func applyEnvOverrides(c *Config) error {
+	v := reflect.ValueOf(c).Elem()
+	for _, field := range configFields() {
+		raw, ok := os.LookupEnv(field.Env)
+		if !ok {
+			continue
+		}
+		if err := parseConfigValue(v.Field(field.Index), raw); err != nil {
+			return fmt.Errorf("invalid %s: %v", field.Env, err)
+		}
+		configSources[field.Key] = sourceEnv
+	}
+	return nil
+}
+
+// applyFlagOverrides copies every config flag given on the command line into the config.
+// Flags are the Args fields sharing a name with a Config field; they are nil when not given.
+// Structured fields without a matching flag type take their flag value as JSON.
+func applyFlagOverrides(c *Config, a *Args) error {
+	v := reflect.ValueOf(c).Elem()
+	av := reflect.ValueOf(a).Elem()
+	for _, field := range configFields() {
+		flag := av.FieldByName(field.Name)
+		if !flag.IsValid() || flag.IsNil() {
+			continue
+		}
+
+		target := v.Field(field.Index)
+		if flag.Kind() == reflect.Ptr {
+			flag = flag.Elem()
+		}
+		if flag.Type().ConvertibleTo(target.Type()) {
+			target.Set(flag.Convert(target.Type()))
+		} else if err := parseConfigValue(target, flag.String()); err != nil {
+			return fmt.Errorf("invalid %s: %v", field.Flag, err)
+		}
+		configSources[field.Key] = sourceFlag
+	}
+	return nil
+}
+
+// applyConfigDefaults fills in defaultConfig values for options that were not set anywhere
+func applyConfigDefaults(c *Config) {
+	v := reflect.ValueOf(c).Elem()
+	defaults := reflect.ValueOf(defaultConfig)
+	for _, field := range configFields() {
+		if _, set := configSources[field.Key]; set || !v.Field(field.Index).IsZero() {
+			continue
+		}
+		if defaults.Field(field.Index).IsZero() {
+			continue
+		}
+		v.Field(field.Index).Set(defaults.Field(field.Index))
+		configSources[field.Key] = sourceDefault
+	}
+}
+
+// parseConfigValue parses an environment variable into a config field of any supported type.
+// Lists of strings or text values may be comma separated; other structured values are given as JSON.
+func parseConfigValue(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		elem := field.Type().Elem()
+		isText := reflect.PointerTo(elem).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem())
+		if (elem.Kind() == reflect.String || isText) && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			items := reflect.MakeSlice(field.Type(), 0, 0)
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				if !isText {
+					items = reflect.Append(items, reflect.ValueOf(item).Convert(elem))
+					continue
+				}
+				value := reflect.New(elem)
+				if err := value.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(item)); err != nil {
+					return err
+				}
+				items = reflect.Append(items, value.Elem())
+			}
+			field.Set(items)
+			return nil
+		}
+		fallthrough
+	default:
+		target := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(raw), target.Interface()); err != nil {
+			return fmt.Errorf("expected JSON: %v", err)
+		}
+		field.Set(target.Elem())
+	}
+	return nil
+}
+
+// showConfig prints the effective config along with where each value came from
+func showConfig() {
+	fmt.Printf("Config file: %s\n", args.Config)
+	if activeProfile != "" {
+		fmt.Printf("Profile: %s\n", activeProfile)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	v := reflect.ValueOf(config)
+	for _, field := range configFields() {
+		source := configSources[field.Key]
+		switch source {
+		case "":
+			source = "unset"
+		case sourceProfile:
+			source += " (" + activeProfile + ")"
+		case sourceEnv:
+			source += " (" + field.Env + ")"
+		case sourceFlag:
+			source += " (" + field.Flag + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", field.Key, formatConfigValue(v.Field(field.Index)), source)
+	}
+	w.Flush()
+}
+
+// formatConfigValue renders a config value for display
+func formatConfigValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return strconv.Quote(value.String())
+	case reflect.Slice, reflect.Map:
+		if value.Len() == 0 {
+			return ""
+		}
+		fallthrough
+	case reflect.Struct:
+		data, _ := json.Marshal(value.Interface())
+		return string(data)
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
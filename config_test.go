@@ -0,0 +1,271 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadConfigFormats tests that JSON, YAML and TOML configs map onto the same Config
+func TestLoadConfigFormats(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"golem-config.json": `{
+    "serverType": "paper",
+    "serverVersion": "1.21.5",
+    "buildNumber": 28,
+    "javaPath": "java",
+    "minRam": "4G",
+    "maxRam": "4G",
+    "serverPath": "./server",
+    "allowExperimentalBuilds": true
+}`,
+		"golem-config.yaml": `# Paper dev server
+serverType: paper
+serverVersion: "1.21.5"
+buildNumber: 28 # pinned until the chunk bug is fixed
+javaPath: java
+minRam: 4G
+maxRam: 4G # the test world needs the headroom
+serverPath: ./server
+allowExperimentalBuilds: true
+`,
+		"golem-config.toml": `# Paper dev server
+serverType = "paper"
+serverVersion = "1.21.5"
+buildNumber = 28 # pinned until the chunk bug is fixed
+javaPath = "java"
+minRam = "4G"
+maxRam = "4G"
+serverPath = "./server"
+allowExperimentalBuilds = true
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(tempDir, name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			config = Config{}
+			if err := loadConfig(path); err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+
+			if config.ServerType != Paper || config.ServerVersion != "1.21.5" || config.BuildNumber != 28 ||
+				config.MaxRAM != "4G" || config.ServerPath != "./server" || !config.AllowExperimentalBuilds {
+				t.Errorf("Unexpected config loaded: %+v", config)
+			}
+		})
+	}
+
+	t.Run("Unquoted YAML version is reported", func(t *testing.T) {
+		path := filepath.Join(tempDir, "bad.yml")
+		content := "serverType: paper\nserverVersion: 1.21\nserverPath: ./server\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		config = Config{}
+		err := loadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), "serverVersion") {
+			t.Errorf("Expected serverVersion type error, got %v", err)
+		}
+	})
+}
+
+// TestSetConfigValue tests that updating the build number keeps the rest of the file intact
+func TestSetConfigValue(t *testing.T) {
+	tempDir := t.TempDir()
+
+	t.Run("JSON keeps key order", func(t *testing.T) {
+		path := filepath.Join(tempDir, "golem-config.json")
+		content := "{\n    \"serverType\": \"paper\",\n    \"buildNumber\": 28,\n    \"serverPath\": \"./server\"\n}"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		if err := setConfigValue(path, 44, "buildNumber"); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		expected := strings.Replace(content, "28", "44", 1)
+		if string(data) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+		}
+	})
+
+	t.Run("YAML keeps comments", func(t *testing.T) {
+		path := filepath.Join(tempDir, "golem-config.yaml")
+		content := "# Paper dev server\nserverType: paper\nbuildNumber: 28 # pinned\nserverPath: ./server\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		if err := setConfigValue(path, 44, "buildNumber"); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		expected := strings.Replace(content, "28", "44", 1)
+		if string(data) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+		}
+	})
+
+	t.Run("TOML keeps comments and inserts missing keys", func(t *testing.T) {
+		path := filepath.Join(tempDir, "golem-config.toml")
+		content := "# Paper dev server\nserverType = \"paper\"\nbuildNumber = 28 # pinned\n\n[extra]\nname = \"x\"\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		if err := setConfigValue(path, 44, "buildNumber"); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+		if err := setConfigValue(path, "./server", "serverPath"); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		expected := "# Paper dev server\nserverType = \"paper\"\nbuildNumber = 44 # pinned\nserverPath = \"./server\"\n\n[extra]\nname = \"x\"\n"
+		if string(data) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+		}
+	})
+}
+
+// TestConfigPrecedence tests that flags override environment variables, which override the file
+func TestConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golem-config.yaml")
+	content := "serverType: paper\nserverVersion: \"1.21.5\"\nminRam: 1G\nmaxRam: 2G\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	origArgs := args
+	defer func() { args = origArgs }()
+
+	maxRAM := "8G"
+	args = Args{MaxRAM: &maxRAM}
+	t.Setenv("GOLEM_MIN_RAM", "3G")
+	t.Setenv("GOLEM_MAX_RAM", "6G")
+	t.Setenv("GOLEM_ALLOW_EXPERIMENTAL_BUILDS", "true")
+
+	if err := loadConfig(path); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expected := map[string]struct {
+		value  interface{}
+		source string
+	}{
+		"serverVersion":           {"1.21.5", sourceFile},
+		"minRam":                  {"3G", sourceEnv},
+		"maxRam":                  {"8G", sourceFlag},
+		"allowExperimentalBuilds": {true, sourceEnv},
+		"serverPath":              {"./server", sourceDefault},
+	}
+	actual := map[string]interface{}{
+		"serverVersion":           config.ServerVersion,
+		"minRam":                  config.MinRAM,
+		"maxRam":                  config.MaxRAM,
+		"allowExperimentalBuilds": config.AllowExperimentalBuilds,
+		"serverPath":              config.ServerPath,
+	}
+
+	for key, want := range expected {
+		if actual[key] != want.value {
+			t.Errorf("%s: expected %v, got %v", key, want.value, actual[key])
+		}
+		if configSources[key] != want.source {
+			t.Errorf("%s: expected source %s, got %s", key, want.source, configSources[key])
+		}
+	}
+}
+
+// TestConfigProfiles tests that profiles layer over the shared values with separate server directories
+func TestConfigProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golem-config.yaml")
+	content := `serverType: paper
+maxRam: 4G
+serverPath: ./servers
+profiles:
+  dev:
+    serverVersion: "1.21.5"
+  folia:
+    serverType: folia
+    serverVersion: "1.21.4"
+    maxRam: 8G
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	origArgs := args
+	defer func() { args = origArgs }()
+
+	t.Run("Profile overrides shared values", func(t *testing.T) {
+		args = Args{Config: path, Profile: "folia"}
+		if err := loadConfig(path); err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+
+		if config.ServerType != Folia || config.ServerVersion != "1.21.4" || config.MaxRAM != "8G" {
+			t.Errorf("Profile values not applied: %+v", config)
+		}
+		if config.ServerPath != filepath.Join("servers", "folia") {
+			t.Errorf("Expected per-profile server path, got %s", config.ServerPath)
+		}
+	})
+
+	t.Run("Build number is saved under the profile", func(t *testing.T) {
+		args = Args{Config: path, Profile: "dev"}
+		if err := loadConfig(path); err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if err := saveBuildNumber(120); err != nil {
+			t.Fatalf("Failed to save build number: %v", err)
+		}
+
+		if err := loadConfig(path); err != nil {
+			t.Fatalf("Failed to reload config: %v", err)
+		}
+		if config.BuildNumber != 120 {
+			t.Errorf("Expected build 120 for dev, got %d", config.BuildNumber)
+		}
+
+		args.Profile = "folia"
+		if err := loadConfig(path); err != nil {
+			t.Fatalf("Failed to reload config: %v", err)
+		}
+		if config.BuildNumber != 0 {
+			t.Errorf("Build number leaked into folia profile: %d", config.BuildNumber)
+		}
+	})
+
+	t.Run("Profile is required when profiles exist", func(t *testing.T) {
+		args = Args{Config: path}
+		if err := loadConfig(path); err == nil {
+			t.Errorf("Expected an error when no profile is selected")
+		}
+	})
+
+	t.Run("Profiles cannot share a server directory", func(t *testing.T) {
+		clash := filepath.Join(t.TempDir(), "golem-config.yaml")
+		content := "serverType: paper\nprofiles:\n  a:\n    serverPath: ./server/b\n  b: {}\n"
+		if err := os.WriteFile(clash, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		args = Args{Config: clash, Profile: "a"}
+		if err := loadConfig(clash); err == nil || !strings.Contains(err.Error(), "serverPath") {
+			t.Errorf("Expected a serverPath clash error, got %v", err)
+		}
+	})
+}
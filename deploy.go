@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Deploy modes for putting watched jars into the server's plugins directory
+const (
+	deployCopy     = "copy"     // Copy the jar and verify the copy
+	deployHardlink = "hardlink" // Hard link the jar, copying when the directories are on different filesystems
+	deploySymlink  = "symlink"  // Link to the jar in the watch directory
+)
+
+var deployModeNames = []string{deployCopy, deployHardlink, deploySymlink}
+
+// validateDeployMode checks the deployMode option
+func validateDeployMode(mode string) error {
+	switch mode {
+	case "", deployCopy, deployHardlink, deploySymlink:
+		return nil
+	}
+	return fmt.Errorf("unknown deployMode %q (available: %s)", mode, strings.Join(deployModeNames, ", "))
+}
+
+// copyPluginJar copies src to dst without the server ever seeing a partial jar: the copy is
+// written to a temporary file next to dst, synced to disk and compared with expectedHash, and
+// only then renamed over dst. A failed copy leaves the previously deployed jar in place.
+func copyPluginJar(src, dst, expectedHash string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	// The server only loads *.jar files, so it ignores the temporary file
+	temp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := io.Copy(temp, source); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to copy %s: %v", filepath.Base(src), err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to sync %s: %v", temp.Name(), err)
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return renameVerified(temp.Name(), dst, expectedHash)
+}
+
+// linkPluginJar links dst to src through a temporary link that is renamed into place, so
+// dst always points at a complete jar. The jar the link leads to is checked against
+// expectedHash like copies.
+func linkPluginJar(src, dst, expectedHash string, symbolic bool) error {
+	// Reserve a unique name for the link, which can't be created over the reserved file
+	reserved, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	temp := reserved.Name()
+	reserved.Close()
+	if err := os.Remove(temp); err != nil {
+		return err
+	}
+	defer os.Remove(temp)
+
+	if symbolic {
+		target, err := filepath.Abs(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, temp); err != nil {
+			return err
+		}
+	} else if err := os.Link(src, temp); err != nil {
+		return err
+	}
+	return renameVerified(temp, dst, expectedHash)
+}
+
+// renameVerified moves temp to dst if its content hashes to expectedHash
+func renameVerified(temp, dst, expectedHash string) error {
+	hash, err := calculateFileHash(temp)
+	if err != nil {
+		return err
+	}
+	if hash != expectedHash {
+		return fmt.Errorf("deployed %s does not match the watched jar (hash %s, expected %s), it may have changed while being deployed",
+			filepath.Base(dst), hash[:8], expectedHash[:min(8, len(expectedHash))])
+	}
+	if err := os.Rename(temp, dst); err != nil {
+		return fmt.Errorf("failed to move %s into place: %v", filepath.Base(dst), err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestUpdatePluginDeployModes(t *testing.T) {
+	origConfig := config
+	defer func() { config = origConfig }()
+
+	watchDir := t.TempDir()
+	jarPath := filepath.Join(watchDir, "MyPlugin.jar")
+	if err := os.WriteFile(jarPath, []byte("plugin v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := calculateFileHash(jarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// leftovers lists files in the plugins directory other than the deployed jar
+	leftovers := func(t *testing.T) []string {
+		t.Helper()
+		entries, err := os.ReadDir(serverPluginsDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, entry := range entries {
+			if entry.Name() != "MyPlugin.jar" {
+				names = append(names, entry.Name())
+			}
+		}
+		return names
+	}
+
+	for _, mode := range deployModeNames {
+		t.Run(mode, func(t *testing.T) {
+			if mode == deploySymlink && runtime.GOOS == "windows" {
+				t.Skip("symlinks need extra privileges on Windows")
+			}
+			config.ServerPath = t.TempDir()
+			config.DeployMode = mode
+
+			// Deploying twice, also at the same time, replaces the jar in place
+			for i := 0; i < 2; i++ {
+				if err := updatePlugin(jarPath, hash); err != nil {
+					t.Fatalf("Failed to deploy: %v", err)
+				}
+			}
+			var wg sync.WaitGroup
+			errs := make([]error, 4)
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs[i] = updatePlugin(jarPath, hash)
+				}(i)
+			}
+			wg.Wait()
+			for _, err := range errs {
+				if err != nil {
+					t.Errorf("Failed to deploy concurrently: %v", err)
+				}
+			}
+			deployed := filepath.Join(serverPluginsDir(), "MyPlugin.jar")
+			data, err := os.ReadFile(deployed)
+			if err != nil || string(data) != "plugin v1" {
+				t.Errorf("Expected the deployed jar to contain the plugin, got %q (%v)", data, err)
+			}
+			info, err := os.Lstat(deployed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isLink := info.Mode()&os.ModeSymlink != 0; isLink != (mode == deploySymlink) {
+				t.Errorf("Deployed jar is a symlink: %v", isLink)
+			}
+			if names := leftovers(t); len(names) != 0 {
+				t.Errorf("Temporary files were left behind: %v", names)
+			}
+		})
+	}
+
+	for _, mode := range deployModeNames {
+		t.Run(mode+" hash mismatch keeps the deployed jar", func(t *testing.T) {
+			if mode == deploySymlink && runtime.GOOS == "windows" {
+				t.Skip("symlinks need extra privileges on Windows")
+			}
+			config.ServerPath = t.TempDir()
+			config.DeployMode = mode
+			if err := updatePlugin(jarPath, hash); err != nil {
+				t.Fatalf("Failed to deploy: %v", err)
+			}
+
+			// The jar changed after it was hashed, e.g. by a build that is still running
+			changedPath := filepath.Join(t.TempDir(), "MyPlugin.jar")
+			if err := os.WriteFile(changedPath, []byte("plugin v2, half written"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := updatePlugin(changedPath, hash); err == nil {
+				t.Errorf("Expected a jar that doesn't match the watched hash to fail")
+			}
+
+			data, err := os.ReadFile(filepath.Join(serverPluginsDir(), "MyPlugin.jar"))
+			if err != nil || string(data) != "plugin v1" {
+				t.Errorf("Expected the previous jar to stay deployed, got %q (%v)", data, err)
+			}
+			if names := leftovers(t); len(names) != 0 {
+				t.Errorf("Temporary files were left behind: %v", names)
+			}
+		})
+	}
+}
+
+func TestIsCrossDeviceError(t *testing.T) {
+	if !isCrossDeviceError(&os.LinkError{Op: "link", Err: syscall.EXDEV}) {
+		t.Errorf("Expected EXDEV to fall back to copying")
+	}
+	if isCrossDeviceError(&os.LinkError{Op: "link", Err: syscall.EPERM}) {
+		t.Errorf("Expected other link errors to be reported")
+	}
+	if isCrossDeviceError(nil) {
+		t.Errorf("Expected no error not to fall back to copying")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDeviceError reports whether a hard link failed because source and target are on
+// different filesystems
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
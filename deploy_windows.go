@@ -0,0 +1,17 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, which Windows returns for links across volumes
+const errorNotSameDevice = syscall.Errno(17)
+
+// isCrossDeviceError reports whether a hard link failed because source and target are on
+// different volumes
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, errorNotSameDevice) || errors.Is(err, syscall.EXDEV)
+}
@@ -2,11 +2,16 @@ module golem
 
 go 1.22.5
 
-require github.com/alexflint/go-arg v1.5.1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/alexflint/go-arg v1.5.1
+	github.com/chzyer/readline v1.5.1
+	github.com/fsnotify/fsnotify v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/charmbracelet/harmonica v0.2.0 // indirect
-	github.com/chzyer/readline v1.5.1 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
 )
 
@@ -14,12 +19,11 @@ require (
 	github.com/alexflint/go-scalar v1.2.0 // indirect
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
 	github.com/charmbracelet/bubbles v0.20.0
-	github.com/charmbracelet/bubbletea v1.2.3 // indirect
-	github.com/charmbracelet/lipgloss v1.0.0 // indirect
+	github.com/charmbracelet/bubbletea v1.2.3
+	github.com/charmbracelet/lipgloss v1.0.0
 	github.com/charmbracelet/x/ansi v0.4.5 // indirect
 	github.com/charmbracelet/x/term v0.2.1 // indirect
 	github.com/erikgeiser/coninput v0.0.0-20211004153227-1c3628e74d0f // indirect
-	github.com/fsnotify/fsnotify v1.8.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-localereader v0.0.1 // indirect
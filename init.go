@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// InitCmd represents the `golem init` subcommand. Values for the new config are taken
+// from the regular config override flags (--server-type, --server-version, ...).
+type InitCmd struct {
+	NonInteractive bool `arg:"--non-interactive" help:"Do not prompt; use flags, detected values and defaults"`
+	Force          bool `arg:"--force" help:"Overwrite an existing config file"`
+}
+
+// maxListedVersions limits how many of the newest versions the wizard lists
+const maxListedVersions = 10
+
+// initWizard asks the questions needed to write a new config, or answers them from flags
+type initWizard struct {
+	reader      *bufio.Reader
+	interactive bool
+}
+
+// runInit creates a new config file at path
+func runInit(cmd *InitCmd, path string) error {
+	if _, err := os.Stat(path); err == nil && !cmd.Force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if _, err := configFormatFor(path); err != nil {
+		return err
+	}
+
+	w := &initWizard{
+		reader:      bufio.NewReader(os.Stdin),
+		interactive: !cmd.NonInteractive,
+	}
+	if w.interactive {
+		fmt.Println("==== Golem project setup ====")
+		fmt.Println("Press enter to accept the suggested value in brackets.")
+	}
+
+	c := Config{}
+
+	// Server type
+	serverType := w.choose("Server type", []string{string(Paper), string(Folia), string(Purpur)},
+		stringFlag(args.ServerType, string(defaultConfig.ServerType)))
+	c.ServerType = ServerType(strings.ToLower(serverType))
+
+	// Server version, newest first
+	versions, err := fetchServerVersions(c.ServerType)
+	if err != nil {
+		log.Printf("Warning: could not fetch %s versions: %v", c.ServerType, err)
+	}
+	defaultVersion := stringFlag(args.ServerVersion, "")
+	if defaultVersion == "" && len(versions) > 0 {
+		defaultVersion = versions[0]
+	}
+	if len(versions) > maxListedVersions {
+		versions = versions[:maxListedVersions]
+	}
+	c.ServerVersion = w.choose("Minecraft version", versions, defaultVersion)
+	if c.ServerVersion == "" {
+		return fmt.Errorf("no server version given, pass --server-version")
+	}
+
+	// Release channel
+	c.AllowExperimentalBuilds = boolFlag(args.AllowExperimentalBuilds, false)
+	if (c.ServerType == Paper || c.ServerType == Folia) && args.AllowExperimentalBuilds == nil {
+		channels := fetchPaperChannels(string(c.ServerType), c.ServerVersion)
+		if len(channels) > 0 {
+			channel := w.choose("Release channel", channels, channels[0])
+			c.AllowExperimentalBuilds = channel != "default"
+		}
+	}
+
+	// Java runtime, suggesting the closest one that can run the chosen version
+	runtimes := findJavaRuntimes()
+	defaultJava := stringFlag(args.JavaPath, defaultConfig.JavaPath)
+	if args.JavaPath == nil && len(runtimes) > 0 {
+		defaultJava = runtimes[0]
+		if compatible := findCompatibleRuntimes(requiredJavaVersion(c.ServerVersion)); len(compatible) > 0 {
+			defaultJava = compatible[0].Path
+		}
+	}
+	c.JavaPath = w.choose("Java runtime", runtimes, defaultJava)
+
+	// Memory and server location
+	c.MinRAM = w.ask("Minimum RAM", stringFlag(args.MinRAM, defaultConfig.MinRAM))
+	c.MaxRAM = w.ask("Maximum RAM", stringFlag(args.MaxRAM, defaultConfig.MaxRAM))
+	c.ServerPath = w.ask("Server directory", stringFlag(args.ServerPath, defaultConfig.ServerPath))
+
+	// Plugin output directory to watch
+	c.Watch = w.ask("Plugin build directory to watch (empty for none)", stringFlag(args.Watch, suggestWatchDir()))
+
+	if err := validateConfig(&c); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+	if err := writeConfigFile(path, &c); err != nil {
+		return fmt.Errorf("failed to write config: %v", err)
+	}
+
+	log.Printf("Wrote %s", path)
+	if c.Watch != "" {
+		log.Println("Start developing with: golem --auto-start")
+	} else {
+		log.Println("Start the server with: golem --auto-start")
+	}
+	return nil
+}
+
+// ask prompts for a free-form answer, returning def when the answer is empty
+func (w *initWizard) ask(question, def string) string {
+	if !w.interactive {
+		return def
+	}
+
+	fmt.Printf("%s [%s]: ", question, def)
+	line, err := w.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return def
+	}
+	if line = strings.TrimSpace(line); line != "" {
+		return line
+	}
+	return def
+}
+
+// choose lists options and prompts for one of them by number or value, returning def when
+// the answer is empty. Values not in the list are accepted as typed.
+func (w *initWizard) choose(question string, options []string, def string) string {
+	if !w.interactive || len(options) == 0 {
+		return w.ask(question, def)
+	}
+
+	fmt.Printf("%s:\n", question)
+	for i, option := range options {
+		marker := " "
+		if option == def {
+			marker = "*"
+		}
+		fmt.Printf(" %s %d) %s\n", marker, i+1, option)
+	}
+
+	answer := w.ask("Choose", def)
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1]
+	}
+	return answer
+}
+
+// fetchServerVersions returns the versions available for a server type, newest first
+func fetchServerVersions(serverType ServerType) ([]string, error) {
+	var versions []string
+	var err error
+	switch serverType {
+	case Paper, Folia:
+		versions, err = fetchPaperVersions(string(serverType))
+	case Purpur:
+		versions, err = fetchPurpurVersions()
+	default:
+		return nil, fmt.Errorf("unsupported server type: %s", serverType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	newest := make([]string, len(versions))
+	for i, version := range versions {
+		newest[len(versions)-1-i] = version
+	}
+	return newest, nil
+}
+
+// fetchPaperChannels returns the release channels with builds for a version, "default" (stable) first
+func fetchPaperChannels(projectName, version string) []string {
+	builds, err := fetchPaperBuilds(projectName, version)
+	if err != nil {
+		log.Printf("Warning: could not fetch %s builds: %v", projectName, err)
+		return nil
+	}
+
+	var channels []string
+	seen := make(map[string]bool)
+	for _, build := range builds.Builds {
+		seen[build.Channel] = true
+	}
+	if seen["default"] {
+		channels = append(channels, "default")
+	}
+	for _, build := range builds.Builds {
+		if build.Channel != "default" && seen[build.Channel] {
+			channels = append(channels, build.Channel)
+			seen[build.Channel] = false
+		}
+	}
+	return channels
+}
+
+// suggestWatchDir guesses the plugin output directory from the build tool in use, suggesting
+// auto detection for multi-module projects
+func suggestWatchDir() string {
+	dirs, _ := detectProjectOutputDirs(".")
+	switch len(dirs) {
+	case 0:
+		return ""
+	case 1:
+		return "./" + filepath.ToSlash(dirs[0])
+	default:
+		return watchAuto
+	}
+}
+
+// stringFlag returns the value of an optional string flag, or def when it was not given
+func stringFlag(flag *string, def string) string {
+	if flag != nil {
+		return *flag
+	}
+	return def
+}
+
+// boolFlag returns the value of an optional boolean flag, or def when it was not given
+func boolFlag(flag *bool, def bool) bool {
+	if flag != nil {
+		return *flag
+	}
+	return def
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// javaRuntime is a java executable along with the version it reports
+type javaRuntime struct {
+	Path    string // Path to the java executable
+	Major   int    // Feature release, e.g. 21 (8 for 1.8)
+	Version string // Full version string as reported by java -version
+}
+
+// defaultManagedJavaVersion is downloaded when the server version does not say which release it needs
+const defaultManagedJavaVersion = 21
+
+var javaVersionPattern = regexp.MustCompile(`version "([^"]+)"`)
+
+// javaExecutable is the name of the java binary on this platform
+func javaExecutable() string {
+	if runtime.GOOS == "windows" {
+		return "java.exe"
+	}
+	return "java"
+}
+
+// findJavaRuntimes returns the java executables installed in well-known locations,
+// starting with JAVA_HOME and the one on PATH
+func findJavaRuntimes() []string {
+	var candidates []string
+
+	if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+		candidates = append(candidates, filepath.Join(javaHome, "bin", javaExecutable()))
+	}
+	if path, err := exec.LookPath("java"); err == nil {
+		candidates = append(candidates, path)
+	}
+
+	// JDK installation directories, each containing one directory per installed runtime
+	patterns := []string{
+		"/usr/lib/jvm/*/bin/java",
+		"/Library/Java/JavaVirtualMachines/*/Contents/Home/bin/java",
+		`C:\Program Files\Java\*\bin\java.exe`,
+		`C:\Program Files\Eclipse Adoptium\*\bin\java.exe`,
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		patterns = append(patterns,
+			filepath.Join(home, ".sdkman", "candidates", "java", "*", "bin", javaExecutable()),
+			filepath.Join(home, ".asdf", "installs", "java", "*", "bin", javaExecutable()),
+		)
+	}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		candidates = append(candidates, matches...)
+	}
+
+	// Deduplicate by resolved path, since PATH and JAVA_HOME usually point at an installed JDK
+	var runtimes []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		resolved, err := filepath.EvalSymlinks(candidate)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(resolved); err != nil || info.IsDir() {
+			continue
+		}
+		if !seen[resolved] {
+			seen[resolved] = true
+			runtimes = append(runtimes, candidate)
+		}
+	}
+
+	return runtimes
+}
+
+// probeJavaRuntime runs java -version to find out which release a java executable is
+func probeJavaRuntime(path string) (javaRuntime, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "-version").CombinedOutput()
+	if err != nil {
+		return javaRuntime{}, fmt.Errorf("failed to run %s -version: %v", path, err)
+	}
+
+	match := javaVersionPattern.FindSubmatch(output)
+	if match == nil {
+		return javaRuntime{}, fmt.Errorf("could not find a version in %s -version output", path)
+	}
+
+	major, err := parseJavaMajor(string(match[1]))
+	if err != nil {
+		return javaRuntime{}, err
+	}
+	return javaRuntime{Path: path, Major: major, Version: string(match[1])}, nil
+}
+
+// parseJavaMajor extracts the feature release from a java version string,
+// handling both the legacy "1.8.0_392" and the modern "21.0.2" schemes
+func parseJavaMajor(version string) (int, error) {
+	parts := strings.FieldsFunc(version, func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || r == '+'
+	})
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("invalid java version %q", version)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid java version %q", version)
+	}
+	if major == 1 && len(parts) > 1 {
+		if major, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, fmt.Errorf("invalid java version %q", version)
+		}
+	}
+	return major, nil
+}
+
+// requiredJavaVersion returns the minimum Java release needed to run a Minecraft version,
+// or 0 when the version is not recognised
+func requiredJavaVersion(minecraftVersion string) int {
+	parts := strings.Split(strings.SplitN(minecraftVersion, "-", 2)[0], ".")
+	numbers := make([]int, 3)
+	for i := 0; i < len(parts) && i < len(numbers); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0
+		}
+		numbers[i] = n
+	}
+
+	major, minor, patch := numbers[0], numbers[1], numbers[2]
+	switch {
+	case major != 1:
+		// Versions after the 1.x line were released well after Java 21 became the requirement
+		return 21
+	case minor > 20 || (minor == 20 && patch >= 5):
+		return 21
+	case minor >= 18:
+		return 17
+	case minor == 17:
+		return 16
+	default:
+		return 8
+	}
+}
+
+// resolveJavaRuntime returns the java executable to launch the server with. The configured
+// javaPath is used when it can run the server version; otherwise a compatible installed
+// runtime is picked when autoSelectJava is enabled, and only when none is installed is a
+// runtime downloaded for managedJava.
+func resolveJavaRuntime() (string, error) {
+	required := requiredJavaVersion(config.ServerVersion)
+
+	configured, err := probeJavaRuntime(config.JavaPath)
+	if err == nil && configured.Major >= required {
+		return config.JavaPath, nil
+	}
+
+	var problem string
+	if err != nil {
+		problem = fmt.Sprintf("javaPath %s could not be used: %v", config.JavaPath, err)
+	} else {
+		problem = fmt.Sprintf("Minecraft %s needs Java %d or newer, but javaPath %s is Java %s",
+			config.ServerVersion, required, config.JavaPath, configured.Version)
+	}
+
+	compatible := findCompatibleRuntimes(required)
+	if len(compatible) == 0 {
+		if config.ManagedJava {
+			return resolveManagedRuntime(problem, required)
+		}
+		return "", fmt.Errorf("%s, and no Java %d+ runtime was found (checked JAVA_HOME, PATH, /usr/lib/jvm, SDKMAN and asdf); "+
+			"install one or enable managedJava to download it", problem, required)
+	}
+
+	if !config.AutoSelectJava {
+		var options []string
+		for _, candidate := range compatible {
+			options = append(options, fmt.Sprintf("%s (Java %s)", candidate.Path, candidate.Version))
+		}
+		return "", fmt.Errorf("%s; set javaPath to a compatible runtime or enable autoSelectJava. Found: %s",
+			problem, strings.Join(options, ", "))
+	}
+
+	selected := compatible[0]
+	log.Printf("%s; using Java %s from %s instead", problem, selected.Version, selected.Path)
+	return selected.Path, nil
+}
+
+// findCompatibleRuntimes probes the installed runtimes and returns those that are at least
+// the required release, closest match first
+func findCompatibleRuntimes(required int) []javaRuntime {
+	var compatible []javaRuntime
+	for _, path := range findJavaRuntimes() {
+		candidate, err := probeJavaRuntime(path)
+		if err != nil {
+			log.Printf("Warning: skipping Java runtime %s: %v", path, err)
+			continue
+		}
+		if candidate.Major >= required {
+			compatible = append(compatible, candidate)
+		}
+	}
+
+	sort.SliceStable(compatible, func(i, j int) bool {
+		return compatible[i].Major < compatible[j].Major
+	})
+	return compatible
+}
+
+// resolveManagedRuntime falls back to a Golem-managed Temurin runtime
+func resolveManagedRuntime(problem string, required int) (string, error) {
+	feature := required
+	if feature == 0 {
+		feature = defaultManagedJavaVersion
+	}
+
+	javaPath, err := ensureManagedRuntime(feature)
+	if err != nil {
+		return "", fmt.Errorf("%s, and the managed Java %d runtime is unavailable: %v", problem, feature, err)
+	}
+
+	log.Printf("%s; using managed Java %d from %s instead", problem, feature, javaPath)
+	return javaPath, nil
+}
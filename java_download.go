@@ -0,0 +1,293 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// AdoptiumAsset is one entry of the Adoptium "latest assets" API response
+type AdoptiumAsset struct {
+	Binary struct {
+		Architecture string `json:"architecture"`
+		ImageType    string `json:"image_type"`
+		OS           string `json:"os"`
+		Package      struct {
+			Checksum string `json:"checksum"`
+			Link     string `json:"link"`
+			Name     string `json:"name"`
+			Size     int64  `json:"size"`
+		} `json:"package"`
+	} `json:"binary"`
+	ReleaseName string `json:"release_name"`
+}
+
+// adoptiumPlatform maps the current platform to Adoptium's os and architecture names
+func adoptiumPlatform() (string, string, error) {
+	osName := runtime.GOOS
+	switch osName {
+	case "darwin":
+		osName = "mac"
+	case "linux", "windows":
+	default:
+		return "", "", fmt.Errorf("no managed Java runtimes for %s", runtime.GOOS)
+	}
+
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x64"
+	case "arm64":
+		arch = "aarch64"
+	case "386":
+		arch = "x32"
+	case "arm":
+	default:
+		return "", "", fmt.Errorf("no managed Java runtimes for %s", runtime.GOARCH)
+	}
+
+	return osName, arch, nil
+}
+
+// managedRuntimesDir is where downloaded Java runtimes are kept
+func managedRuntimesDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find cache directory: %v", err)
+	}
+	return filepath.Join(cacheDir, "golem", "java"), nil
+}
+
+// ensureManagedRuntime returns the java executable of a Temurin JRE for the given feature
+// release, downloading it from Adoptium into Golem's cache the first time
+func ensureManagedRuntime(feature int) (string, error) {
+	osName, arch, err := adoptiumPlatform()
+	if err != nil {
+		return "", err
+	}
+
+	baseDir, err := managedRuntimesDir()
+	if err != nil {
+		return "", err
+	}
+	installDir := filepath.Join(baseDir, fmt.Sprintf("temurin-%d-%s-%s", feature, osName, arch))
+
+	// Reuse a runtime downloaded earlier
+	if javaPath, err := findJavaBinary(installDir); err == nil {
+		return javaPath, nil
+	}
+
+	// Ask Adoptium for the latest JRE of this release
+	assetsURL := fmt.Sprintf("%s/v3/assets/latest/%d/hotspot?architecture=%s&image_type=jre&os=%s&vendor=eclipse",
+		strings.TrimSuffix(config.AdoptiumURL, "/"), feature, url.QueryEscape(arch), url.QueryEscape(osName))
+	var assets []AdoptiumAsset
+	if err := fetchJSON(assetsURL, &assets); err != nil {
+		return "", fmt.Errorf("failed to fetch Java %d release info: %v", feature, err)
+	}
+	if len(assets) == 0 {
+		return "", fmt.Errorf("no Temurin %d JRE available for %s/%s", feature, osName, arch)
+	}
+	asset := assets[0]
+	pkg := asset.Binary.Package
+
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create runtime cache: %v", err)
+	}
+
+	log.Printf("Downloading Temurin %s (%s, SHA256: %s)", asset.ReleaseName, pkg.Name, pkg.Checksum)
+	archivePath := filepath.Join(baseDir, pkg.Name+".tmp")
+	if err := downloadFile(pkg.Link, archivePath); err != nil {
+		os.Remove(archivePath)
+		return "", fmt.Errorf("failed to download Java runtime: %v", err)
+	}
+	defer os.Remove(archivePath)
+
+	if err := verifyFileHash(archivePath, pkg.Checksum); err != nil {
+		return "", fmt.Errorf("downloaded Java runtime checksum mismatch: %v", err)
+	}
+
+	// Unpack next to the final location and move it into place once complete
+	tmpDir := installDir + ".tmp"
+	os.RemoveAll(tmpDir)
+	if strings.HasSuffix(pkg.Name, ".zip") {
+		err = extractZip(archivePath, tmpDir)
+	} else {
+		err = extractTarGz(archivePath, tmpDir)
+	}
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to unpack Java runtime: %v", err)
+	}
+
+	os.RemoveAll(installDir)
+	if err := os.Rename(tmpDir, installDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to move Java runtime into place: %v", err)
+	}
+
+	javaPath, err := findJavaBinary(installDir)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Installed Temurin %s to %s", asset.ReleaseName, installDir)
+	return javaPath, nil
+}
+
+// findJavaBinary locates bin/java inside an unpacked runtime
+func findJavaBinary(dir string) (string, error) {
+	patterns := []string{
+		filepath.Join(dir, "*", "bin", javaExecutable()),
+		filepath.Join(dir, "*", "Contents", "Home", "bin", javaExecutable()),
+	}
+	for _, pattern := range patterns {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+	return "", fmt.Errorf("no java executable found in %s", dir)
+}
+
+// safeJoin joins an archive entry name onto dir, rejecting entries that would escape it
+func safeJoin(dir, name string) (string, error) {
+	target := filepath.Join(dir, name)
+	if !withinDir(dir, target) {
+		return "", fmt.Errorf("archive entry %q escapes the target directory", name)
+	}
+	return target, nil
+}
+
+// withinDir reports whether path is dir or lies inside it
+func withinDir(dir, path string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// checkNoSymlinks makes sure no existing component of target below dir, including target
+// itself, is a symlink, so an archive can't write through a link it created earlier
+func checkNoSymlinks(dir, target string) error {
+	dir = filepath.Clean(dir)
+	for path := target; path != dir && withinDir(dir, path); path = filepath.Dir(path) {
+		info, err := os.Lstat(path)
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("archive entry %q would be written through a symlink", target)
+		}
+	}
+	return nil
+}
+
+// extractTarGz unpacks a .tar.gz archive into dir, keeping file modes and symlinks
+func extractTarGz(archivePath, dir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := checkNoSymlinks(dir, target); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, reader, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// Links must be relative and point inside the runtime
+			if filepath.IsAbs(header.Linkname) || !withinDir(dir, filepath.Join(filepath.Dir(target), header.Linkname)) {
+				return fmt.Errorf("archive symlink %q points outside the target directory", header.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extractZip unpacks a .zip archive into dir
+func extractZip(archivePath, dir string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, entry := range reader.File {
+		target, err := safeJoin(dir, entry.Name)
+		if err != nil {
+			return err
+		}
+		if err := checkNoSymlinks(dir, target); err != nil {
+			return err
+		}
+
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		source, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveFile(target, source, entry.Mode())
+		source.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeArchiveFile writes one unpacked file, creating its parent directories
+func writeArchiveFile(target string, source io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, source)
+	return err
+}
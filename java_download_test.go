@@ -0,0 +1,99 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestTarGz writes a .tar.gz archive with the given entries
+func writeTestTarGz(t *testing.T, path string, headers []tar.Header) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	defer gz.Close()
+	writer := tar.NewWriter(gz)
+	defer writer.Close()
+
+	for _, header := range headers {
+		header.Mode = 0644
+		content := []byte("payload")
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(content))
+		}
+		if err := writer.WriteHeader(&header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := writer.Write(content); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestExtractTarGzRejectsEscapingSymlinks(t *testing.T) {
+	tests := map[string][]tar.Header{
+		"absolute symlink": {
+			{Name: "jdk/link", Typeflag: tar.TypeSymlink, Linkname: "OUTSIDE"},
+			{Name: "jdk/link/victim", Typeflag: tar.TypeReg},
+		},
+		"relative symlink": {
+			{Name: "jdk/link", Typeflag: tar.TypeSymlink, Linkname: "../../../outside"},
+			{Name: "jdk/link/victim", Typeflag: tar.TypeReg},
+		},
+		"entry below a symlink": {
+			{Name: "jdk/bin", Typeflag: tar.TypeSymlink, Linkname: "lib"},
+			{Name: "jdk/bin/victim", Typeflag: tar.TypeReg},
+		},
+	}
+
+	for name, headers := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			outside := filepath.Join(root, "outside")
+			if err := os.MkdirAll(outside, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for i := range headers {
+				if headers[i].Linkname == "OUTSIDE" {
+					headers[i].Linkname = outside
+				}
+			}
+			archive := filepath.Join(root, "jdk.tar.gz")
+			writeTestTarGz(t, archive, headers)
+
+			if err := extractTarGz(archive, filepath.Join(root, "runtime", "unpacked")); err == nil {
+				t.Errorf("Expected the archive to be rejected")
+			}
+			if _, err := os.Stat(filepath.Join(outside, "victim")); err == nil {
+				t.Errorf("A file was written outside the target directory")
+			}
+		})
+	}
+}
+
+func TestExtractTarGz(t *testing.T) {
+	root := t.TempDir()
+	archive := filepath.Join(root, "jdk.tar.gz")
+	writeTestTarGz(t, archive, []tar.Header{
+		{Name: "jdk/", Typeflag: tar.TypeDir},
+		{Name: "jdk/lib/libjvm.so", Typeflag: tar.TypeReg},
+		{Name: "jdk/bin/libjvm.so", Typeflag: tar.TypeSymlink, Linkname: "../lib/libjvm.so"},
+	})
+
+	dir := filepath.Join(root, "unpacked")
+	if err := extractTarGz(archive, dir); err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "jdk", "bin", "libjvm.so"))
+	if err != nil || string(data) != "payload" {
+		t.Errorf("Expected the symlink to resolve inside the runtime, got %q, %v", data, err)
+	}
+}
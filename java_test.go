@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestParseJavaMajor tests version parsing for both Java version schemes
+func TestParseJavaMajor(t *testing.T) {
+	cases := map[string]int{
+		"1.8.0_392":  8,
+		"11.0.22":    11,
+		"17":         17,
+		"21.0.2":     21,
+		"22-ea":      22,
+		"21.0.1+12":  21,
+		"1.7.0_80-b": 7,
+	}
+
+	for version, expected := range cases {
+		major, err := parseJavaMajor(version)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", version, err)
+		} else if major != expected {
+			t.Errorf("%s: expected %d, got %d", version, expected, major)
+		}
+	}
+
+	if _, err := parseJavaMajor("openjdk"); err == nil {
+		t.Errorf("Expected an error for an invalid version")
+	}
+}
+
+// TestRequiredJavaVersion tests the minimum Java release for Minecraft versions
+func TestRequiredJavaVersion(t *testing.T) {
+	cases := map[string]int{
+		"1.16.5":      8,
+		"1.17.1":      16,
+		"1.18":        17,
+		"1.20.4":      17,
+		"1.20.5":      21,
+		"1.21":        21,
+		"1.21.5":      21,
+		"1.21.5-pre1": 21,
+		"":            0,
+		"snapshot":    0,
+	}
+
+	for version, expected := range cases {
+		if required := requiredJavaVersion(version); required != expected {
+			t.Errorf("%q: expected Java %d, got %d", version, expected, required)
+		}
+	}
+}
+
+// TestResolveJavaRuntimeInstalledBeforeManaged tests that managedJava does not download a
+// runtime when a compatible one is installed but autoSelectJava is off
+func TestResolveJavaRuntimeInstalledBeforeManaged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake java runtime is a shell script")
+	}
+	origConfig := config
+	defer func() { config = origConfig }()
+
+	// A JDK 21 in JAVA_HOME that only answers -version
+	javaHome := t.TempDir()
+	javaPath := filepath.Join(javaHome, "bin", "java")
+	if err := os.MkdirAll(filepath.Dir(javaPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho 'openjdk version \"21.0.2\" 2024-01-16' >&2\n"
+	if err := os.WriteFile(javaPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JAVA_HOME", javaHome)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	adoptium := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected Adoptium request %s", r.URL)
+		http.NotFound(w, r)
+	}))
+	defer adoptium.Close()
+
+	config.ServerVersion = "1.21"
+	config.JavaPath = filepath.Join(t.TempDir(), "missing-java")
+	config.ManagedJava = true
+	config.AutoSelectJava = false
+	config.AdoptiumURL = adoptium.URL
+
+	_, err := resolveJavaRuntime()
+	if err == nil || !strings.Contains(err.Error(), "enable autoSelectJava") || !strings.Contains(err.Error(), javaPath) {
+		t.Errorf("Expected the installed runtime to be suggested, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// jvmPresets are named sets of JVM flags that can be selected with jvmPreset
+var jvmPresets = map[string][]string{
+	// Aikar's G1 tuning, see https://docs.papermc.io/paper/aikars-flags
+	"aikar": {
+		"-XX:+UseG1GC",
+		"-XX:+ParallelRefProcEnabled",
+		"-XX:MaxGCPauseMillis=200",
+		"-XX:+UnlockExperimentalVMOptions",
+		"-XX:+DisableExplicitGC",
+		"-XX:+AlwaysPreTouch",
+		"-XX:G1NewSizePercent=30",
+		"-XX:G1MaxNewSizePercent=40",
+		"-XX:G1HeapRegionSize=8M",
+		"-XX:G1ReservePercent=20",
+		"-XX:G1HeapWastePercent=5",
+		"-XX:G1MixedGCCountTarget=4",
+		"-XX:InitiatingHeapOccupancyPercent=15",
+		"-XX:G1MixedGCLiveThresholdPercent=90",
+		"-XX:G1RSetUpdatingPauseTimePercent=5",
+		"-XX:SurvivorRatio=32",
+		"-XX:+PerfDisableSharedMem",
+		"-XX:MaxTenuringThreshold=1",
+		"-Dusing.aikars.flags=https://mcflags.emc.gs",
+		"-Daikars.new.flags=true",
+	},
+	// Trades peak throughput for a quicker boot, which is what matters when restarting on every build
+	"fast-dev": {
+		"-XX:TieredStopAtLevel=1",
+		"-XX:+UseSerialGC",
+		"-Xshare:auto",
+		"-XX:-UsePerfData",
+	},
+}
+
+// jvmPresetNames returns the names of the available JVM presets
+func jvmPresetNames() []string {
+	return sortedKeys(jvmPresets)
+}
+
+// buildServerCommand assembles the java arguments and environment used to launch the server
+func buildServerCommand() ([]string, []string) {
+	javaArgs := []string{
+		"-Xms" + config.MinRAM,
+		"-Xmx" + config.MaxRAM,
+	}
+	javaArgs = append(javaArgs, jvmPresets[config.JVMPreset]...)
+	javaArgs = append(javaArgs, config.JVMArgs...)
+	javaArgs = append(javaArgs, "-jar", "server.jar", "nogui")
+	javaArgs = append(javaArgs, config.ServerArgs...)
+
+	env := os.Environ()
+	for _, key := range sortedKeys(config.Env) {
+		env = append(env, key+"="+config.Env[key])
+	}
+
+	return javaArgs, env
+}
+
+// formatCommandLine renders a command for logging, quoting arguments that contain spaces
+func formatCommandLine(name string, cmdArgs []string) string {
+	parts := []string{quoteArg(name)}
+	for _, arg := range cmdArgs {
+		parts = append(parts, quoteArg(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteArg(arg string) string {
+	if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+		return fmt.Sprintf("%q", arg)
+	}
+	return arg
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"io"
 	"log"
 	"os"
 
@@ -14,6 +13,7 @@ type ServerType string
 const (
 	Vanilla ServerType = "vanilla"
 	Paper   ServerType = "paper"
+	Folia   ServerType = "folia"
 	Purpur  ServerType = "purpur"
 )
 
@@ -27,37 +27,134 @@ type Config struct {
 	MaxRAM                  string     `json:"maxRam"`
 	ServerPath              string     `json:"serverPath"`
 	AllowExperimentalBuilds bool       `json:"allowExperimentalBuilds"`
+	Watch                   string     `json:"watch,omitempty"`
+	AutoSelectJava          bool       `json:"autoSelectJava,omitempty"` // Use another installed runtime when javaPath is too old
+	AcceptEULA              bool       `json:"acceptEula,omitempty"`     // Agree to the Minecraft EULA without asking
+	ManagedJava             bool       `json:"managedJava,omitempty"`    // Download a Temurin runtime when no installed one fits
+	AdoptiumURL             string     `json:"adoptiumUrl,omitempty"`    // Adoptium API base URL, for mirrors
+
+	// Plugin development watch mode
+	WatchPaths       []WatchPath             `json:"watchPaths,omitempty"`       // More directories to watch, each with optional recursion and include/exclude globs
+	DeployMode       string                  `json:"deployMode,omitempty"`       // How jars reach the plugins directory: copy, hardlink or symlink
+	WatchPolling     bool                    `json:"watchPolling,omitempty"`     // Rescan the watch directory every 2s instead of using filesystem events
+	WatchQuietPeriod string                  `json:"watchQuietPeriod,omitempty"` // How long a jar must stay unchanged before it is deployed
+	WatchDebounce    string                  `json:"watchDebounce,omitempty"`    // How long to collect plugin changes before one deploy and restart
+	WatchKeepDeleted bool                    `json:"watchKeepDeleted,omitempty"` // Leave deployed plugins in place when their jar is deleted from the watch directory
+	ProvidedPlugins  []string                `json:"providedPlugins,omitempty"`  // Plugins installed some other way, which watched plugins may depend on
+	ReloadStrategy   string                  `json:"reloadStrategy,omitempty"`   // How plugin changes reach the running server: restart, commands, reload or copy-only
+	ReloadCommands   []string                `json:"reloadCommands,omitempty"`   // Console commands for the "commands" strategy
+	PluginReload     map[string]PluginReload `json:"pluginReload,omitempty"`     // Per-plugin reload settings, by plugin name
+	BuildCommand     string                  `json:"buildCommand,omitempty"`     // Build to run when the sources change, e.g. "./gradlew build"
+	BuildSources     []string                `json:"buildSources,omitempty"`     // Directories whose changes trigger the build
+
+	// Server launch
+	JVMPreset     string            `json:"jvmPreset,omitempty"`     // Named set of JVM flags, see jvmPresets
+	JVMArgs       []string          `json:"jvmArgs,omitempty"`       // Extra JVM arguments, after the preset
+	ServerArgs    []string          `json:"serverArgs,omitempty"`    // Arguments passed to the server after nogui
+	Env           map[string]string `json:"env,omitempty"`           // Extra environment variables for the server
+	ReadyPatterns []string          `json:"readyPatterns,omitempty"` // Regular expressions for the output line that shows the server has started
+	ReadyTimeout  string            `json:"readyTimeout,omitempty"`  // How long to wait for a ready line before treating the server as started
+
+	// Server files managed by Golem
+	ServerProperties ServerProperties `json:"serverProperties,omitempty"` // Values merged into server.properties before each start
+	ConfigOverrides  ConfigOverrides  `json:"configOverrides,omitempty"`  // Values set in the server's YAML config files before each start
 }
 
 // Args represents command-line arguments
 type Args struct {
-	Config    string `arg:"--config" help:"Path to config file"`
-	Watch     string `arg:"--watch" help:"Path to plugin development directory to watch"`
+	Config    string `arg:"--config" help:"Path to config file (.json, .yaml, .yml or .toml)"`
+	Profile   string `arg:"--profile,env:GOLEM_PROFILE" help:"Name of the config profile to use"`
 	AutoStart bool   `arg:"--auto-start" help:"Automatically start server after update"`
+
+	// Config overrides, named after the Config field they replace (nil when not given)
+	ServerType              *string           `arg:"--server-type" help:"Override serverType"`
+	ServerVersion           *string           `arg:"--server-version" help:"Override serverVersion"`
+	BuildNumber             *int              `arg:"--build-number" help:"Override buildNumber"`
+	JavaPath                *string           `arg:"--java-path" help:"Override javaPath"`
+	MinRAM                  *string           `arg:"--min-ram" help:"Override minRam"`
+	MaxRAM                  *string           `arg:"--max-ram" help:"Override maxRam"`
+	ServerPath              *string           `arg:"--server-path" help:"Override serverPath"`
+	AllowExperimentalBuilds *bool             `arg:"--allow-experimental-builds" help:"Override allowExperimentalBuilds"`
+	Watch                   *string           `arg:"--watch" help:"Path to plugin development directory to watch, or auto to watch every Gradle or Maven module's output"`
+	WatchPaths              []WatchPath       `arg:"--watch-paths,separate" help:"Override watchPaths; repeat for each directory, as a path or a JSON object"`
+	DeployMode              *string           `arg:"--deploy-mode" help:"Override deployMode (copy, hardlink, symlink)"`
+	WatchPolling            *bool             `arg:"--watch-polling" help:"Override watchPolling"`
+	WatchQuietPeriod        *string           `arg:"--watch-quiet-period" help:"Override watchQuietPeriod, e.g. 500ms"`
+	WatchDebounce           *string           `arg:"--watch-debounce" help:"Override watchDebounce, e.g. 1s"`
+	WatchKeepDeleted        *bool             `arg:"--watch-keep-deleted" help:"Override watchKeepDeleted"`
+	ProvidedPlugins         []string          `arg:"--provided-plugins,separate" help:"Override providedPlugins; repeat for each plugin name"`
+	ReloadStrategy          *string           `arg:"--reload-strategy" help:"Override reloadStrategy (restart, commands, reload, copy-only)"`
+	ReloadCommands          []string          `arg:"--reload-commands,separate" help:"Override reloadCommands; repeat for each command"`
+	PluginReload            *string           `arg:"--plugin-reload" help:"Override pluginReload, as JSON"`
+	BuildCommand            *string           `arg:"--build-command" help:"Override buildCommand"`
+	BuildSources            []string          `arg:"--build-sources,separate" help:"Override buildSources; repeat for each directory"`
+	AutoSelectJava          *bool             `arg:"--auto-select-java" help:"Override autoSelectJava"`
+	AcceptEULA              *bool             `arg:"--accept-eula" help:"Agree to the Minecraft EULA (https://aka.ms/MinecraftEULA)"`
+	ManagedJava             *bool             `arg:"--managed-java" help:"Override managedJava"`
+	AdoptiumURL             *string           `arg:"--adoptium-url" help:"Override adoptiumUrl"`
+	JVMPreset               *string           `arg:"--jvm-preset" help:"Override jvmPreset (aikar, fast-dev)"`
+	JVMArgs                 []string          `arg:"--jvm-args,separate" help:"Override jvmArgs; repeat for each argument, e.g. --jvm-args=-XX:+UseZGC"`
+	ServerArgs              []string          `arg:"--server-args,separate" help:"Override serverArgs; repeat for each argument, e.g. --server-args=--port=25566"`
+	Env                     map[string]string `arg:"--env,separate" help:"Override env; repeat for each KEY=VALUE pair"`
+	ReadyPatterns           []string          `arg:"--ready-patterns,separate" help:"Override readyPatterns; repeat for each regular expression"`
+	ReadyTimeout            *string           `arg:"--ready-timeout" help:"Override readyTimeout, e.g. 5m"`
+	ServerProperties        map[string]string `arg:"--server-properties,separate" help:"Override serverProperties; repeat for each KEY=VALUE pair"`
+	ConfigOverrides         *string           `arg:"--config-overrides" help:"Override configOverrides, as JSON"`
+
+	Init      *InitCmd   `arg:"subcommand:init" help:"Create a config file for this project"`
+	ConfigCmd *ConfigCmd `arg:"subcommand:config" help:"Inspect the effective configuration"`
+}
+
+// ConfigCmd represents the `golem config` subcommands
+type ConfigCmd struct {
+	Show *struct{} `arg:"subcommand:show" help:"Print the merged config and where each value came from"`
+}
+
+func (Args) Description() string {
+	return "Config values are resolved as flags > GOLEM_* environment variables > config file > defaults."
 }
 
 var config Config
 var args Args
-var serverProcess *os.Process
-var serverStdin io.WriteCloser
 
 func main() {
 	log.Println("Golem - The minecraft server manager and watcher  |  version 0.1.0")
-	arg.MustParse(&args)
+	parser := arg.MustParse(&args)
 
 	// Load configuration
+	if args.Init != nil {
+		if args.Config == "" {
+			args.Config = defaultConfigFiles[0]
+		}
+		if err := runInit(args.Init, args.Config); err != nil {
+			log.Fatalf("Failed to create config: %v", err)
+		}
+		return
+	}
+
 	if args.Config == "" {
-		args.Config = "golem-config.json"
+		args.Config = findConfigFile()
 	}
 
 	if err := loadConfig(args.Config); err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	if args.ConfigCmd != nil {
+		if args.ConfigCmd.Show == nil {
+			parser.FailSubcommand("missing subcommand", "config")
+		}
+		showConfig()
+		return
+	}
+
 	// Handle development watch mode
-	if args.Watch != "" {
-		log.Printf("Starting development watch mode for directory: %s", args.Watch)
-		watchPluginDevelopment(args.Watch)
+	if config.Watch != "" || len(config.WatchPaths) > 0 {
+		watchPaths, err := resolveWatchPaths()
+		if err != nil {
+			log.Fatalf("Failed to start watch mode: %v", err)
+		}
+		watchPluginDevelopment(watchPaths)
 		return
 	}
 
@@ -69,10 +166,11 @@ func main() {
 	}
 
 	if args.AutoStart {
-		if err := startServer(); err != nil {
+		if err := server.Start(); err != nil {
 			log.Fatalf("Failed to start server: %v", err)
 		}
-		
+		startConsole()
+
 		// Block here to keep Golem running as a middleman
 		// This will only exit if the process is killed or an exit command is given
 		waitForExit := make(chan struct{})
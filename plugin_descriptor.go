@@ -0,0 +1,176 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PluginDescriptor is the metadata a plugin jar declares about itself
+type PluginDescriptor struct {
+	File       string // Descriptor the metadata was read from, e.g. "plugin.yml"
+	Name       string // Plugin name, or the plugin id for Velocity plugins
+	Version    string
+	Main       string
+	APIVersion string
+	Depend     []string // Plugins that must be loaded first
+	SoftDepend []string // Plugins that are loaded first when present
+	LoadBefore []string // Plugins that must be loaded after this one
+	Commands   []string
+}
+
+// String returns the plugin name with its version, e.g. "MyPlugin 1.2.0"
+func (d *PluginDescriptor) String() string {
+	if d.Version == "" {
+		return d.Name
+	}
+	return d.Name + " " + d.Version
+}
+
+// pluginDescriptorFiles are the descriptors looked for in a jar, in order of preference.
+// Paper itself prefers paper-plugin.yml when a jar has both.
+var pluginDescriptorFiles = []string{"paper-plugin.yml", "plugin.yml", "velocity-plugin.json"}
+
+// bukkitDescriptor is the layout of plugin.yml
+type bukkitDescriptor struct {
+	Name       string               `yaml:"name"`
+	Version    string               `yaml:"version"`
+	Main       string               `yaml:"main"`
+	APIVersion string               `yaml:"api-version"`
+	Depend     []string             `yaml:"depend"`
+	SoftDepend []string             `yaml:"softdepend"`
+	LoadBefore []string             `yaml:"loadbefore"`
+	Commands   map[string]yaml.Node `yaml:"commands"`
+}
+
+// paperDescriptor is the layout of paper-plugin.yml
+type paperDescriptor struct {
+	Name         string `yaml:"name"`
+	Version      string `yaml:"version"`
+	Main         string `yaml:"main"`
+	APIVersion   string `yaml:"api-version"`
+	Dependencies struct {
+		Server map[string]struct {
+			Load     string `yaml:"load"`
+			Required *bool  `yaml:"required"`
+		} `yaml:"server"`
+	} `yaml:"dependencies"`
+}
+
+// velocityDescriptor is the layout of velocity-plugin.json
+type velocityDescriptor struct {
+	ID           string `json:"id"`
+	Version      string `json:"version"`
+	Main         string `json:"main"`
+	Dependencies []struct {
+		ID       string `json:"id"`
+		Optional bool   `json:"optional"`
+	} `json:"dependencies"`
+}
+
+// readPluginDescriptor reads the plugin metadata from a jar
+func readPluginDescriptor(jarPath string) (*PluginDescriptor, error) {
+	reader, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	for _, name := range pluginDescriptorFiles {
+		file, err := reader.Open(name)
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+
+		descriptor, err := parsePluginDescriptor(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		if descriptor.Name == "" {
+			return nil, fmt.Errorf("%s does not declare a plugin name", name)
+		}
+		return descriptor, nil
+	}
+
+	return nil, fmt.Errorf("no plugin.yml, paper-plugin.yml or velocity-plugin.json found")
+}
+
+// parsePluginDescriptor parses the content of one of the pluginDescriptorFiles
+func parsePluginDescriptor(file string, data []byte) (*PluginDescriptor, error) {
+	switch file {
+	case "plugin.yml":
+		var raw bukkitDescriptor
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		return &PluginDescriptor{
+			File:       file,
+			Name:       raw.Name,
+			Version:    raw.Version,
+			Main:       raw.Main,
+			APIVersion: raw.APIVersion,
+			Depend:     raw.Depend,
+			SoftDepend: raw.SoftDepend,
+			LoadBefore: raw.LoadBefore,
+			Commands:   sortedKeys(raw.Commands),
+		}, nil
+
+	case "paper-plugin.yml":
+		var raw paperDescriptor
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		descriptor := &PluginDescriptor{
+			File:       file,
+			Name:       raw.Name,
+			Version:    raw.Version,
+			Main:       raw.Main,
+			APIVersion: raw.APIVersion,
+		}
+		// "load: AFTER" makes the dependency load after this plugin; anything else is
+		// treated like depend or softdepend. Dependencies are required unless stated otherwise.
+		for _, name := range sortedKeys(raw.Dependencies.Server) {
+			dependency := raw.Dependencies.Server[name]
+			required := dependency.Required == nil || *dependency.Required
+			switch {
+			case dependency.Load == "AFTER":
+				descriptor.LoadBefore = append(descriptor.LoadBefore, name)
+			case required:
+				descriptor.Depend = append(descriptor.Depend, name)
+			default:
+				descriptor.SoftDepend = append(descriptor.SoftDepend, name)
+			}
+		}
+		return descriptor, nil
+
+	case "velocity-plugin.json":
+		var raw velocityDescriptor
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		descriptor := &PluginDescriptor{
+			File:    file,
+			Name:    raw.ID,
+			Version: raw.Version,
+			Main:    raw.Main,
+		}
+		for _, dependency := range raw.Dependencies {
+			if dependency.Optional {
+				descriptor.SoftDepend = append(descriptor.SoftDepend, dependency.ID)
+			} else {
+				descriptor.Depend = append(descriptor.Depend, dependency.ID)
+			}
+		}
+		return descriptor, nil
+	}
+
+	return nil, fmt.Errorf("unknown plugin descriptor %s", file)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadPluginDescriptor(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  PluginDescriptor
+	}{
+		{
+			name: "plugin.yml",
+			files: map[string]string{"plugin.yml": `name: MyPlugin
+version: 1.2
+main: com.example.MyPlugin
+api-version: 1.20
+depend: [Vault]
+softdepend: [PlaceholderAPI, LuckPerms]
+loadbefore: [Essentials]
+commands:
+  spawn:
+    description: Teleport to spawn
+  home: {}
+`},
+			want: PluginDescriptor{
+				File:       "plugin.yml",
+				Name:       "MyPlugin",
+				Version:    "1.2",
+				Main:       "com.example.MyPlugin",
+				APIVersion: "1.20",
+				Depend:     []string{"Vault"},
+				SoftDepend: []string{"PlaceholderAPI", "LuckPerms"},
+				LoadBefore: []string{"Essentials"},
+				Commands:   []string{"home", "spawn"},
+			},
+		},
+		{
+			name: "paper-plugin.yml preferred",
+			files: map[string]string{
+				"plugin.yml": "name: Legacy\nversion: 0.1\nmain: com.example.Legacy\n",
+				"paper-plugin.yml": `name: Modern
+version: 2.0.0
+main: com.example.Modern
+api-version: '1.21'
+dependencies:
+  server:
+    Vault:
+      load: BEFORE
+    PlaceholderAPI:
+      load: BEFORE
+      required: false
+    Essentials:
+      load: AFTER
+      required: false
+`},
+			want: PluginDescriptor{
+				File:       "paper-plugin.yml",
+				Name:       "Modern",
+				Version:    "2.0.0",
+				Main:       "com.example.Modern",
+				APIVersion: "1.21",
+				Depend:     []string{"Vault"},
+				SoftDepend: []string{"PlaceholderAPI"},
+				LoadBefore: []string{"Essentials"},
+			},
+		},
+		{
+			name: "velocity-plugin.json",
+			files: map[string]string{"velocity-plugin.json": `{"id":"myproxy","name":"MyProxy","version":"1.0.0",
+"main":"com.example.MyProxy","dependencies":[{"id":"luckperms","optional":true},{"id":"core","optional":false}]}`},
+			want: PluginDescriptor{
+				File:       "velocity-plugin.json",
+				Name:       "myproxy",
+				Version:    "1.0.0",
+				Main:       "com.example.MyProxy",
+				Depend:     []string{"core"},
+				SoftDepend: []string{"luckperms"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, test.name+".jar")
+			writeTestJar(t, path, test.files)
+
+			got, err := readPluginDescriptor(path)
+			if err != nil {
+				t.Fatalf("Failed to read descriptor: %v", err)
+			}
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("Expected %+v, got %+v", test.want, *got)
+			}
+		})
+	}
+
+	t.Run("rejects jars without a descriptor", func(t *testing.T) {
+		path := filepath.Join(dir, "library.jar")
+		writeTestJar(t, path, map[string]string{"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\n"})
+		if _, err := readPluginDescriptor(path); err == nil {
+			t.Errorf("Expected a jar without plugin.yml to be rejected")
+		}
+	})
+
+	t.Run("rejects descriptors without a name", func(t *testing.T) {
+		path := filepath.Join(dir, "nameless.jar")
+		writeTestJar(t, path, map[string]string{"plugin.yml": "version: 1.0\nmain: com.example.Main\n"})
+		if _, err := readPluginDescriptor(path); err == nil {
+			t.Errorf("Expected a plugin.yml without a name to be rejected")
+		}
+	})
+}
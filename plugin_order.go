@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependencyOrder sorts plugins so that each one comes after the plugins it depends on or
+// soft-depends on, and before the plugins it lists in loadbefore, the way the server loads
+// them. Plugins that are not ordered by a dependency keep their name order, and descriptors
+// sharing a plugin name, like two versions of a jar, stay together in their given order. When
+// the dependencies are circular, the plugins on the cycle are appended in name order and the
+// first cycle found is returned, e.g. [A B A].
+func dependencyOrder(descriptors []*PluginDescriptor) ([]*PluginDescriptor, []string) {
+	byName := make(map[string][]*PluginDescriptor)
+	for _, d := range descriptors {
+		name := strings.ToLower(d.Name)
+		byName[name] = append(byName[name], d)
+	}
+
+	// after[x] holds the plugins that must come after x
+	after := make(map[string][]string)
+	waiting := make(map[string]int)
+	addEdge := func(first, then string) {
+		first, then = strings.ToLower(first), strings.ToLower(then)
+		if _, ok := byName[first]; !ok || first == then || containsFold(after[first], then) {
+			return
+		}
+		after[first] = append(after[first], then)
+		waiting[then]++
+	}
+	for _, d := range descriptors {
+		for _, dep := range append(append([]string(nil), d.Depend...), d.SoftDepend...) {
+			addEdge(dep, d.Name)
+		}
+		for _, other := range d.LoadBefore {
+			if _, ok := byName[strings.ToLower(other)]; ok {
+				addEdge(d.Name, other)
+			}
+		}
+	}
+
+	var ready []string
+	for name := range byName {
+		if waiting[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	var ordered []*PluginDescriptor
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, byName[name]...)
+		delete(byName, name)
+
+		for _, next := range after[name] {
+			if waiting[next]--; waiting[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	if len(byName) == 0 {
+		return ordered, nil
+	}
+
+	// Everything left is on a cycle or waits for one
+	remaining := sortedKeys(byName)
+	cycle := findCycle(remaining[0], after, byName)
+	for _, name := range remaining {
+		ordered = append(ordered, byName[name]...)
+	}
+	return ordered, cycle
+}
+
+// findCycle follows dependency edges between the unordered plugins from start until a
+// plugin repeats, and returns the plugin names along the cycle
+func findCycle(start string, after map[string][]string, unordered map[string][]*PluginDescriptor) []string {
+	// Walk backwards along "must come after" edges: each unordered plugin waits for another one
+	before := make(map[string]string)
+	for from, targets := range after {
+		if _, ok := unordered[from]; !ok {
+			continue
+		}
+		for _, to := range targets {
+			if existing, ok := before[to]; !ok || from < existing {
+				before[to] = from
+			}
+		}
+	}
+
+	seen := make(map[string]int)
+	var path []string
+	for name := start; ; name = before[name] {
+		if index, ok := seen[name]; ok {
+			path = append(path[index:], name)
+			break
+		}
+		seen[name] = len(path)
+		path = append(path, name)
+	}
+
+	// Report the cycle in dependency order, with the plugins' own spelling
+	cycle := make([]string, len(path))
+	for i, name := range path {
+		cycle[len(path)-1-i] = unordered[name][0].Name
+	}
+	return cycle
+}
+
+// orderPluginChanges returns a batch in deploy order: removals first, then added and changed
+// plugins after their dependencies, so reload commands run in an order that can succeed
+func orderPluginChanges(pending map[string]*pluginChange) []*pluginChange {
+	var ordered, updates []*pluginChange
+	var descriptors []*PluginDescriptor
+	byDescriptor := make(map[*PluginDescriptor]*pluginChange)
+	for _, name := range sortedKeys(pending) {
+		change := pending[name]
+		if change.Kind == pluginRemoved {
+			ordered = append(ordered, change)
+			continue
+		}
+		descriptors = append(descriptors, change.Descriptor)
+		byDescriptor[change.Descriptor] = change
+	}
+
+	sorted, _ := dependencyOrder(descriptors)
+	for _, d := range sorted {
+		updates = append(updates, byDescriptor[d])
+	}
+	return append(ordered, updates...)
+}
+
+// checkPluginDependencies warns about dependency problems that would stop the server from
+// loading the watched plugins: circular dependencies, and hard dependencies of the changed
+// plugins that are not watched, listed in providedPlugins or installed in the plugins directory
+func (w *pluginWatcher) checkPluginDependencies(changes []*pluginChange) {
+	var watched []*PluginDescriptor
+	available := make(map[string]bool)
+	for _, name := range sortedKeys(w.plugins) {
+		watched = append(watched, w.plugins[name].Descriptor)
+		available[strings.ToLower(w.plugins[name].Descriptor.Name)] = true
+	}
+	for _, name := range config.ProvidedPlugins {
+		available[strings.ToLower(name)] = true
+	}
+
+	if _, cycle := dependencyOrder(watched); cycle != nil {
+		printWithPrompt(fmt.Sprintf("[Golem] Warning: circular plugin dependency %s, the server will not load these plugins",
+			strings.Join(cycle, " → ")))
+	}
+
+	// The changed plugins, and the watched ones that depend on a removed plugin
+	var check []*PluginDescriptor
+	for _, change := range changes {
+		if change.Kind != pluginRemoved {
+			check = append(check, change.Descriptor)
+			continue
+		}
+		for _, d := range watched {
+			if containsFold(d.Depend, change.Descriptor.Name) {
+				check = append(check, d)
+			}
+		}
+	}
+
+	var installed map[string]bool
+	for _, d := range check {
+		for _, dep := range d.Depend {
+			if available[strings.ToLower(dep)] {
+				continue
+			}
+			if installed == nil {
+				installed = installedPluginNames()
+			}
+			if installed[strings.ToLower(dep)] {
+				continue
+			}
+			printWithPrompt(fmt.Sprintf("[Golem] Warning: %s depends on %s, which is not watched, listed in providedPlugins "+
+				"or installed in plugins; the server will not load %s", d.Name, dep, d.Name))
+		}
+	}
+}
+
+// containsFold reports whether names contains name, ignoring case
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
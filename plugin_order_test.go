@@ -0,0 +1,155 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	names := func(descriptors []*PluginDescriptor) []string {
+		var result []string
+		for _, d := range descriptors {
+			result = append(result, d.Name)
+		}
+		return result
+	}
+
+	t.Run("dependencies first", func(t *testing.T) {
+		ordered, cycle := dependencyOrder([]*PluginDescriptor{
+			{Name: "Addon", Depend: []string{"Core"}, SoftDepend: []string{"Metrics"}},
+			{Name: "Core", Depend: []string{"Vault"}},
+			{Name: "Metrics"},
+			{Name: "Early", LoadBefore: []string{"core"}},
+		})
+		if cycle != nil {
+			t.Errorf("Unexpected cycle %v", cycle)
+		}
+		want := []string{"Early", "Core", "Metrics", "Addon"}
+		if got := names(ordered); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		ordered, cycle := dependencyOrder([]*PluginDescriptor{
+			{Name: "A", Depend: []string{"B"}},
+			{Name: "B", Depend: []string{"C"}},
+			{Name: "C", Depend: []string{"A"}},
+			{Name: "D", Depend: []string{"A"}},
+			{Name: "Free"},
+		})
+		if want := []string{"Free", "A", "B", "C", "D"}; !reflect.DeepEqual(names(ordered), want) {
+			t.Errorf("Expected %v, got %v", want, names(ordered))
+		}
+		if len(cycle) != 4 || cycle[0] != cycle[3] {
+			t.Fatalf("Expected a cycle of three plugins, got %v", cycle)
+		}
+		for _, name := range cycle {
+			if name == "D" {
+				t.Errorf("D only waits for the cycle and is not on it: %v", cycle)
+			}
+		}
+	})
+}
+
+// TestPluginWatcherDependencies tests deploy order and missing dependency warnings
+func TestPluginWatcherDependencies(t *testing.T) {
+	watchDir := t.TempDir()
+	origConfig := config
+	config.ServerPath = t.TempDir()
+	config.ProvidedPlugins = []string{"LuckPerms"}
+	defer func() { config = origConfig }()
+
+	// Vault is installed in the server by hand
+	if err := os.MkdirAll(serverPluginsDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestJar(t, filepath.Join(serverPluginsDir(), "Vault.jar"), map[string]string{"plugin.yml": "name: Vault\n"})
+
+	writeTestJar(t, filepath.Join(watchDir, "A-Addon.jar"), map[string]string{"plugin.yml": "name: Addon\ndepend: [Core, Missing]\n"})
+	writeTestJar(t, filepath.Join(watchDir, "Z-Core.jar"), map[string]string{"plugin.yml": "name: Core\ndepend: [Vault, LuckPerms]\n"})
+
+	watcher := newPluginWatcher([]WatchPath{{Path: watchDir}}, 0, 0)
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var order []string
+	for _, change := range watcher.deploy() {
+		order = append(order, change.Descriptor.Name)
+	}
+	if want := []string{"Core", "Addon"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected deploy order %v, got %v", want, order)
+	}
+
+	// printWithPrompt writes to stdout, so check the warnings through the same function
+	warnings := captureStdout(t, func() {
+		watcher.checkPluginDependencies([]*pluginChange{{Kind: pluginModified, Descriptor: watcher.plugins["A-Addon.jar"].Descriptor}})
+	})
+	if !strings.Contains(warnings, "Addon depends on Missing") {
+		t.Errorf("Expected a warning about Missing, got %q", warnings)
+	}
+	if strings.Contains(warnings, "depends on Core") || strings.Contains(warnings, "Vault") || strings.Contains(warnings, "LuckPerms") {
+		t.Errorf("Watched, installed and provided dependencies should not be reported: %q", warnings)
+	}
+}
+
+// TestPluginWatcherDuplicatePluginNames tests that jars declaring the same plugin are all
+// deployed, with the last one by file name ending up in the server
+func TestPluginWatcherDuplicatePluginNames(t *testing.T) {
+	watchDir := t.TempDir()
+	origConfig := config
+	config.ServerPath = t.TempDir()
+	defer func() { config = origConfig }()
+
+	// Gradle leaves the previous version next to the new one in build/libs
+	writeTestJar(t, filepath.Join(watchDir, "MyPlugin-1.0.jar"), map[string]string{"plugin.yml": "name: MyPlugin\nversion: 1.0\n"})
+	writeTestJar(t, filepath.Join(watchDir, "MyPlugin-1.1.jar"), map[string]string{"plugin.yml": "name: MyPlugin\nversion: 1.1\n"})
+
+	watcher := newPluginWatcher([]WatchPath{{Path: watchDir}}, 0, 0)
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if applied := watcher.deploy(); len(applied) != 2 {
+		t.Errorf("Expected both jars to be deployed, got %d", len(applied))
+	}
+	if len(watcher.pending) != 0 {
+		t.Errorf("No change should be left pending, got %v", sortedKeys(watcher.pending))
+	}
+
+	// Nothing is left over to downgrade the plugin on the next scan
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	watcher.deploy()
+	if _, err := os.Stat(filepath.Join(serverPluginsDir(), "MyPlugin-1.1.jar")); err != nil {
+		t.Errorf("Expected MyPlugin-1.1.jar to stay deployed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(serverPluginsDir(), "MyPlugin-1.0.jar")); !os.IsNotExist(err) {
+		t.Errorf("Expected MyPlugin-1.0.jar to have been replaced")
+	}
+}
+
+// captureStdout returns what fn prints to stdout, where printWithPrompt writes
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+	fn()
+	writer.Close()
+	return <-output
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// disabledPluginsDir is where Golem moves jars it takes out of the server's plugins directory.
+// The server only loads jars directly inside plugins, so they are ignored there.
+const disabledPluginsDir = ".golem-disabled"
+
+// serverPluginsDir returns the server's plugins directory
+func serverPluginsDir() string {
+	return filepath.Join(config.ServerPath, "plugins")
+}
+
+// disablePluginJar moves a jar from the server's plugins directory into disabledPluginsDir,
+// replacing an earlier jar of the same name there
+func disablePluginJar(path string) error {
+	dir := filepath.Join(serverPluginsDir(), disabledPluginsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+
+	target := filepath.Join(dir, filepath.Base(path))
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %v", filepath.Base(path), dir, err)
+	}
+	return nil
+}
+
+// removeStalePluginJars disables jars in the server's plugins directory that contain the same
+// plugin as descriptor under another file name, e.g. myplugin-1.0.0.jar when
+// myplugin-1.0.1.jar is about to be deployed. The server refuses to load duplicate plugins.
+func removeStalePluginJars(descriptor *PluginDescriptor, fileName string) error {
+	files, err := os.ReadDir(serverPluginsDir())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read plugins directory: %v", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || file.Name() == fileName || !isJarFile(file.Name()) {
+			continue
+		}
+
+		path := filepath.Join(serverPluginsDir(), file.Name())
+		deployed, err := readPluginDescriptor(path)
+		if err != nil || !strings.EqualFold(deployed.Name, descriptor.Name) {
+			continue
+		}
+
+		if err := disablePluginJar(path); err != nil {
+			return err
+		}
+		log.Printf("Moved %s (%s) to plugins/%s, it is replaced by %s", file.Name(), deployed, disabledPluginsDir, fileName)
+	}
+	return nil
+}
+
+// installedPluginNames returns the lower-cased names of the plugins in the server's plugins
+// directory, read from their descriptors
+func installedPluginNames() map[string]bool {
+	names := make(map[string]bool)
+	files, err := os.ReadDir(serverPluginsDir())
+	if err != nil {
+		return names
+	}
+	for _, file := range files {
+		if file.IsDir() || !isJarFile(file.Name()) {
+			continue
+		}
+		if descriptor, err := readPluginDescriptor(filepath.Join(serverPluginsDir(), file.Name())); err == nil {
+			names[strings.ToLower(descriptor.Name)] = true
+		}
+	}
+	return names
+}
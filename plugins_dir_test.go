@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveStalePluginJars(t *testing.T) {
+	origServerPath := config.ServerPath
+	config.ServerPath = t.TempDir()
+	defer func() { config.ServerPath = origServerPath }()
+
+	pluginsDir := serverPluginsDir()
+	if err := os.MkdirAll(pluginsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestJar(t, filepath.Join(pluginsDir, "myplugin-1.0.0.jar"), map[string]string{"plugin.yml": "name: MyPlugin\nversion: 1.0.0\n"})
+	writeTestJar(t, filepath.Join(pluginsDir, "other-1.0.0.jar"), map[string]string{"plugin.yml": "name: Other\nversion: 1.0.0\n"})
+	writeTestJar(t, filepath.Join(pluginsDir, "myplugin-1.0.1.jar"), map[string]string{"plugin.yml": "name: MyPlugin\nversion: 1.0.1\n"})
+
+	descriptor := &PluginDescriptor{Name: "MyPlugin", Version: "1.0.1"}
+	if err := removeStalePluginJars(descriptor, "myplugin-1.0.1.jar"); err != nil {
+		t.Fatalf("Failed to remove stale jars: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(pluginsDir, "myplugin-1.0.0.jar")); !os.IsNotExist(err) {
+		t.Errorf("Older jar of the same plugin should have been moved out of plugins")
+	}
+	if _, err := os.Stat(filepath.Join(pluginsDir, disabledPluginsDir, "myplugin-1.0.0.jar")); err != nil {
+		t.Errorf("Older jar should have been moved to %s: %v", disabledPluginsDir, err)
+	}
+	for _, keep := range []string{"myplugin-1.0.1.jar", "other-1.0.0.jar"} {
+		if _, err := os.Stat(filepath.Join(pluginsDir, keep)); err != nil {
+			t.Errorf("%s should have been kept: %v", keep, err)
+		}
+	}
+}
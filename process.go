@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -10,7 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-	"time"
+	"sync/atomic"
 
 	"github.com/chzyer/readline"
 )
@@ -19,7 +18,10 @@ import (
 var outputMutex sync.Mutex
 var promptActive bool
 var instance *readline.Instance
-var processDown chan struct{} // Channel to signal when the server process has exited
+
+// Subscribers to the server output, see subscribeServerOutput
+var outputSubscribers = make(map[chan string]struct{})
+var subscribersMutex sync.Mutex
 
 // No problematic server-side tab completion
 
@@ -90,295 +92,169 @@ func printWithPrompt(message string) {
 	promptActive = true
 }
 
-func startServer() error {
-	if serverProcess != nil {
-		return fmt.Errorf("server is already running")
-	}
+// subscribeServerOutput returns a channel receiving every line the server prints, and a
+// function to stop receiving them. Lines are dropped if the subscriber falls behind.
+func subscribeServerOutput() (<-chan string, func()) {
+	ch := make(chan string, 256)
+	subscribersMutex.Lock()
+	outputSubscribers[ch] = struct{}{}
+	subscribersMutex.Unlock()
 
-	jarPath := filepath.Join(config.ServerPath, "server.jar")
-	if _, err := os.Stat(jarPath); os.IsNotExist(err) {
-		return fmt.Errorf("server jar not found at %s", jarPath)
+	return ch, func() {
+		subscribersMutex.Lock()
+		delete(outputSubscribers, ch)
+		subscribersMutex.Unlock()
 	}
+}
 
-	javaArgs := []string{
-		"-Xms" + config.MinRAM,
-		"-Xmx" + config.MaxRAM,
-		"-jar",
-		"server.jar",
-		"nogui",
+// publishServerOutput passes a line of server output to the subscribers
+func publishServerOutput(line string) {
+	subscribersMutex.Lock()
+	defer subscribersMutex.Unlock()
+	for ch := range outputSubscribers {
+		select {
+		case ch <- line:
+		default:
+		}
 	}
+}
 
-	// Start the server process directly so pipes work correctly on all platforms
-	cmd := exec.Command(config.JavaPath, javaArgs...)
-	cmd.Dir = config.ServerPath
-
-	// Set up pipes to capture server I/O
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %v", err)
+// launchServer prepares the server for a start and returns the command that runs it
+func launchServer() (*exec.Cmd, error) {
+	jarPath := filepath.Join(config.ServerPath, "server.jar")
+	if _, err := os.Stat(jarPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("server jar not found at %s", jarPath)
 	}
 
-	stderr, err := cmd.StderrPipe()
+	// Make sure the Java runtime can run this server version before launching
+	javaPath, err := resolveJavaRuntime()
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %v", err)
+		return nil, err
 	}
 
-	// Set up stdin pipe for server control
-	serverStdin, err = cmd.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdin pipe: %v", err)
+	// Bring server.properties in line with the config
+	if err := applyServerProperties(); err != nil {
+		return nil, err
+	}
+	if err := applyConfigOverrides(); err != nil {
+		return nil, err
 	}
 
-	// Accept EULA before starting
+	// Make sure the EULA has been agreed to before starting
 	if err := acceptEULA(); err != nil {
-		return fmt.Errorf("failed to accept EULA: %v", err)
+		return nil, err
 	}
 
-	// Start the process BEFORE setting up the goroutines to read from it
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start server: %v", err)
-	}
+	javaArgs, env := buildServerCommand()
 
-	serverProcess = cmd.Process
-	log.Printf("Server started with PID %d", serverProcess.Pid)
+	// Start the server process directly so pipes work correctly on all platforms
+	cmd := exec.Command(javaPath, javaArgs...)
+	cmd.Dir = config.ServerPath
+	cmd.Env = env
 
-	// Initialize the processDown channel
-	processDown = make(chan struct{})
-	
-	// Monitor the process in background first to signal other goroutines
-	go func() {
-		state, err := cmd.Process.Wait()
-		if err != nil {
-			log.Printf("Server exited with error: %v", err)
-		} else if !state.Success() {
-			log.Printf("Server exited with code %d", state.ExitCode())
-		} else {
-			log.Println("Server stopped gracefully")
-		}
-		
-		// Signal other goroutines to stop
-		close(processDown)
-		
-		// Clean up process variables
-		serverProcess = nil
-		serverStdin = nil
-	}()
-	
-	// NOW start the goroutines to capture output after the process is running
-	go func() {
-		defer func() {
-			// Recover from any panics that might occur if pipes are closed
-			if r := recover(); r != nil {
-				log.Printf("Recovered from panic in stdout reader: %v", r)
-			}
-		}()
-		
-		scanner := bufio.NewScanner(stdout)
-		scanner.Buffer(make([]byte, 4096), 256*1024) // Increase buffer size
-		
-		for {
-			select {
-			case <-processDown:
-				// Process is down, exit goroutine
-				return
-			default:
-				// Read next line if available
-				if !scanner.Scan() {
-					// End of output or error
-					if err := scanner.Err(); err != nil && err != io.EOF {
-						log.Printf("Error reading server stdout: %v", err)
-					}
-					return
-				}
-				printWithPrompt(fmt.Sprintf("[Server] %s", scanner.Text()))
-			}
-		}
-	}()
-	
-	go func() {
-		defer func() {
-			// Recover from any panics that might occur if pipes are closed
-			if r := recover(); r != nil {
-				log.Printf("Recovered from panic in stderr reader: %v", r)
-			}
-		}()
-		
-		scanner := bufio.NewScanner(stderr)
-		scanner.Buffer(make([]byte, 4096), 256*1024) // Increase buffer size
-		
-		for {
-			select {
-			case <-processDown:
-				// Process is down, exit goroutine
-				return
-			default:
-				// Read next line if available
-				if !scanner.Scan() {
-					// End of output or error
-					if err := scanner.Err(); err != nil && err != io.EOF {
-						log.Printf("Error reading server stderr: %v", err)
-					}
-					return
-				}
-				printWithPrompt(fmt.Sprintf("[Server Error] %s", scanner.Text()))
-			}
-		}
-	}()
-	
-	// Setup readline for tab completion and better input handling
-	var rlErr error
-	instance, rlErr = readline.NewEx(&readline.Config{
-		Prompt:       "golem> ",
-		AutoComplete: &GolemCompleter{},
-		// Prevent readline from directly exiting on Ctrl-C
-		InterruptPrompt: "^C",
-		EOFPrompt:      "exit",
-	})
-	if rlErr != nil {
-		return fmt.Errorf("failed to initialize readline: %v", rlErr)
+	log.Printf("Launching: %s", formatCommandLine(javaPath, javaArgs))
+	// Only the names of extra variables are logged, their values may be secrets
+	if len(config.Env) > 0 {
+		log.Printf("  with environment variables %s", strings.Join(sortedKeys(config.Env), ", "))
 	}
-	
-	// Start a goroutine to handle user input with readline
-	go func() {
-		// Initial welcome message
-		fmt.Println("\nGolem is now running. Type commands to send to Minecraft server.")
-		fmt.Println("Special Golem commands: !help, !exit, !stop, !restart")
-		fmt.Println("Use TAB for command completion")
-		
-		defer instance.Close()
-		
-		for {
-			// Read a line with tab completion
-			line, err := instance.Readline()
-			if err != nil {
-				// Handle readline specific errors
-				if err == readline.ErrInterrupt {
-					// Ctrl-C pressed, but don't exit
-					continue
-				} else if err == io.EOF {
-					// EOF (Ctrl-D) - exit gracefully
-					fmt.Println("Exiting Golem...")
-					if serverProcess != nil {
-						stopServer()
-					}
-					os.Exit(0)
-				} else {
-					// Other error
-					log.Printf("Error reading input: %v", err)
-					break
-				}
-			}
-			
-			// Process command
-			// Handle special Golem commands
-			switch line {
-			case "!help":
-				fmt.Println("\nGolem Commands:")
-				fmt.Println("  !help     - Display this help message")
-				fmt.Println("  !exit     - Stop the server and exit Golem")
-				fmt.Println("  !stop     - Stop the server but keep Golem running")
-				fmt.Println("  !restart  - Restart the Minecraft server")
-				fmt.Println("\nAll other commands are sent directly to the Minecraft server.")
-				
-			case "!exit":
-				fmt.Println("Stopping server and exiting Golem...")
-				stopServer()
-				os.Exit(0)
-				
-			case "!stop":
-				fmt.Println("Stopping server only...")
-				stopServer()
-				
-			case "!restart":
-				fmt.Println("Restarting server...")
-				restartServer()
-				
-			default:
-				// Check if server is still running before sending commands
-				if serverProcess == nil || serverStdin == nil {
-					fmt.Println("Server is not running. Use !restart to start it again.")
-					continue
-				}
-				
-				// Send the command to the Minecraft server
-				_, err := serverStdin.Write([]byte(line + "\n"))
-				if err != nil {
-					fmt.Printf("Error sending command to server: %v\n", err)
-				}
-			}
+	return cmd, nil
+}
+
+// consoleOnce makes sure only one console reads from the terminal, however often the
+// server is restarted
+var consoleOnce sync.Once
+
+// consoleStarted is set once the console reads from the terminal, after which nothing else may
+var consoleStarted atomic.Bool
+
+// startConsole reads commands from the terminal and passes them to the server, handling
+// Golem's own ! commands
+func startConsole() {
+	consoleOnce.Do(func() {
+		// Setup readline for tab completion and better input handling
+		var rlErr error
+		instance, rlErr = readline.NewEx(&readline.Config{
+			Prompt:       "golem> ",
+			AutoComplete: &GolemCompleter{},
+			// Prevent readline from directly exiting on Ctrl-C
+			InterruptPrompt: "^C",
+			EOFPrompt:       "exit",
+		})
+		if rlErr != nil {
+			log.Printf("Failed to initialize readline, console input is disabled: %v", rlErr)
+			return
 		}
-		
-		log.Println("Input handler exited")
-	}()
-// Process monitoring has been moved to the top of this function
-	// No need to monitor again here
 
-	return nil
+		consoleStarted.Store(true)
+		go runConsole()
+	})
 }
 
-func stopServer() error {
-	if serverProcess == nil {
-		return nil
-	}
+// runConsole is the console input loop
+func runConsole() {
+	// Initial welcome message
+	fmt.Println("\nGolem is now running. Type commands to send to Minecraft server.")
+	fmt.Println("Special Golem commands: !help, !exit, !stop, !restart")
+	fmt.Println("Use TAB for command completion")
 
-	log.Println("Stopping server gracefully...")
+	defer instance.Close()
 
-	// Send 'stop' command to server stdin
-	if serverStdin != nil {
-		if _, err := serverStdin.Write([]byte("stop\n")); err != nil {
-			return fmt.Errorf("failed to send stop command: %v", err)
+	for {
+		// Read a line with tab completion
+		line, err := instance.Readline()
+		if err != nil {
+			// Handle readline specific errors
+			if err == readline.ErrInterrupt {
+				// Ctrl-C pressed, but don't exit
+				continue
+			} else if err == io.EOF {
+				// EOF (Ctrl-D) - exit gracefully
+				fmt.Println("Exiting Golem...")
+				server.Stop()
+				os.Exit(0)
+			} else {
+				// Other error
+				log.Printf("Error reading input: %v", err)
+				break
+			}
 		}
-	}
 
-	// Wait for the process to exit with a timeout
-	done := make(chan error, 1)
-	go func() {
-		_, err := serverProcess.Wait()
-		done <- err
-	}()
+		// Handle special Golem commands
+		switch line {
+		case "!help":
+			fmt.Println("\nGolem Commands:")
+			fmt.Println("  !help     - Display this help message")
+			fmt.Println("  !exit     - Stop the server and exit Golem")
+			fmt.Println("  !stop     - Stop the server but keep Golem running")
+			fmt.Println("  !restart  - Restart the Minecraft server")
+			fmt.Println("\nAll other commands are sent directly to the Minecraft server.")
 
-	select {
-	case err := <-done:
-		serverProcess = nil
-		serverStdin = nil
-		return err
-	case <-time.After(30 * time.Second):
-		// Force kill if server doesn't stop gracefully
-		if err := serverProcess.Kill(); err != nil {
-			return fmt.Errorf("failed to force kill server: %v", err)
-		}
-		serverProcess = nil
-		serverStdin = nil
-		return fmt.Errorf("server did not stop gracefully within timeout")
-	}
-}
+		case "!exit":
+			fmt.Println("Stopping server and exiting Golem...")
+			server.Stop()
+			os.Exit(0)
 
-func restartServer() error {
-	// Only try to stop if there's a server process running
-	if serverProcess != nil {
-		log.Println("Stopping server before restart...")
-		if err := stopServer(); err != nil {
-			// If it failed because there's no process, that's fine
-			if !strings.Contains(err.Error(), "no child processes") {
-				log.Printf("Warning during server stop: %v", err)
+		case "!stop":
+			fmt.Println("Stopping server only...")
+			if err := server.Stop(); err != nil {
+				log.Printf("Failed to stop server: %v", err)
 			}
-		}
-		// Make sure these are nil to avoid issues
-		serverProcess = nil
-		serverStdin = nil
-	} else {
-		log.Println("No server process found to stop, starting fresh")
-	}
 
-	// Wait a bit for the server to fully stop and resources to be released
-	log.Println("Waiting 3 seconds before restart...")
-	time.Sleep(3 * time.Second)
+		case "!restart":
+			fmt.Println("Restarting server...")
+			if err := server.Restart(); err != nil {
+				log.Printf("Failed to restart server: %v", err)
+			}
 
-	// Start the server
-	log.Println("Starting server...")
-	if err := startServer(); err != nil {
-		return fmt.Errorf("failed to restart server: %v", err)
+		default:
+			// Send the command to the Minecraft server
+			if err := server.Send(line); err == errServerNotRunning {
+				fmt.Println("Server is not running. Use !restart to start it again.")
+			} else if err != nil {
+				fmt.Printf("Error sending command to server: %v\n", err)
+			}
+		}
 	}
 
-	return nil
+	log.Println("Input handler exited")
 }
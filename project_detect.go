@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// watchAuto is the watch value that makes Golem find the build output directories itself
+const watchAuto = "auto"
+
+// skippedProjectDirs are never searched for modules
+var skippedProjectDirs = map[string]bool{
+	"build": true, "buildSrc": true, "target": true, "out": true, "bin": true, "src": true, "node_modules": true,
+}
+
+// detectProjectOutputDirs finds the jar output directory of every Gradle or Maven module in
+// the project at root: build/libs next to each build.gradle(.kts), or target next to each
+// pom.xml. The directories don't have to exist yet.
+func detectProjectOutputDirs(root string) ([]string, string) {
+	tool, buildFiles, output := "", []string(nil), ""
+	switch {
+	case anyFileExists(root, "settings.gradle", "settings.gradle.kts", "build.gradle", "build.gradle.kts"):
+		tool, buildFiles, output = "Gradle", []string{"build.gradle", "build.gradle.kts"}, filepath.Join("build", "libs")
+	case anyFileExists(root, "pom.xml"):
+		tool, buildFiles, output = "Maven", []string{"pom.xml"}, "target"
+	default:
+		return nil, ""
+	}
+
+	var dirs []string
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if path != root && (strings.HasPrefix(entry.Name(), ".") || skippedProjectDirs[entry.Name()]) {
+			return filepath.SkipDir
+		}
+		if anyFileExists(path, buildFiles...) {
+			dirs = append(dirs, filepath.Join(path, output))
+		}
+		return nil
+	})
+	sort.Strings(dirs)
+	return dirs, tool
+}
+
+// anyFileExists reports whether dir contains at least one of the named files
+func anyFileExists(dir string, names ...string) bool {
+	for _, name := range names {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectProjectOutputDirs(t *testing.T) {
+	touch := func(t *testing.T, path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("gradle multi-module", func(t *testing.T) {
+		root := t.TempDir()
+		touch(t, filepath.Join(root, "settings.gradle.kts"))
+		touch(t, filepath.Join(root, "build.gradle.kts"))
+		touch(t, filepath.Join(root, "api", "build.gradle.kts"))
+		touch(t, filepath.Join(root, "plugin", "build.gradle"))
+		touch(t, filepath.Join(root, "docs", "README.md"))
+		touch(t, filepath.Join(root, "buildSrc", "build.gradle.kts"))
+		touch(t, filepath.Join(root, ".gradle", "build.gradle"))
+
+		dirs, tool := detectProjectOutputDirs(root)
+		want := []string{
+			filepath.Join(root, "api", "build", "libs"),
+			filepath.Join(root, "build", "libs"),
+			filepath.Join(root, "plugin", "build", "libs"),
+		}
+		if tool != "Gradle" || !reflect.DeepEqual(dirs, want) {
+			t.Errorf("Expected Gradle %v, got %s %v", want, tool, dirs)
+		}
+	})
+
+	t.Run("maven", func(t *testing.T) {
+		root := t.TempDir()
+		touch(t, filepath.Join(root, "pom.xml"))
+		touch(t, filepath.Join(root, "core", "pom.xml"))
+		touch(t, filepath.Join(root, "target", "classes", "pom.xml"))
+
+		dirs, tool := detectProjectOutputDirs(root)
+		want := []string{filepath.Join(root, "core", "target"), filepath.Join(root, "target")}
+		if tool != "Maven" || !reflect.DeepEqual(dirs, want) {
+			t.Errorf("Expected Maven %v, got %s %v", want, tool, dirs)
+		}
+	})
+
+	t.Run("no project", func(t *testing.T) {
+		if dirs, tool := detectProjectOutputDirs(t.TempDir()); len(dirs) != 0 || tool != "" {
+			t.Errorf("Expected nothing to be detected, got %s %v", tool, dirs)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ServerProperties holds server.properties values. Booleans and numbers are accepted as
+// well as strings, so `online-mode: false` can be written unquoted in YAML.
+type ServerProperties map[string]string
+
+// UnmarshalJSON merges the decoded values into the existing map, so profiles can override
+// individual properties
+func (p *ServerProperties) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if *p == nil {
+		*p = ServerProperties{}
+	}
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			(*p)[key] = v
+		case float64, bool:
+			(*p)[key] = fmt.Sprint(v)
+		case nil:
+			(*p)[key] = ""
+		default:
+			return fmt.Errorf("server property %s must be a string, number or boolean", key)
+		}
+	}
+	return nil
+}
+
+// propertyChange describes one server.properties value changed by Golem
+type propertyChange struct {
+	Key      string
+	Old      string
+	New      string
+	Inserted bool
+}
+
+// applyServerProperties merges the configured serverProperties into server.properties,
+// keeping unrelated keys and comments, and prints what changed
+func applyServerProperties() error {
+	if len(config.ServerProperties) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(config.ServerPath, "server.properties")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read server.properties: %v", err)
+	}
+
+	updated, changes := mergeProperties(string(data), config.ServerProperties)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write server.properties: %v", err)
+	}
+
+	log.Println("Updated server.properties:")
+	for _, change := range changes {
+		if change.Inserted {
+			log.Printf("  + %s=%s", change.Key, change.New)
+		} else {
+			log.Printf("  ~ %s: %s → %s", change.Key, change.Old, change.New)
+		}
+	}
+	return nil
+}
+
+// mergeProperties sets values in properties file content. Existing keys are updated in place
+// and missing ones are appended in sorted order; every other line is left untouched.
+func mergeProperties(content string, values map[string]string) (string, []propertyChange) {
+	var changes []propertyChange
+	seen := make(map[string]bool)
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		key, value, ok := parsePropertyLine(line)
+		if !ok {
+			continue
+		}
+		desired, managed := values[key]
+		if !managed {
+			continue
+		}
+
+		seen[key] = true
+		if value != desired {
+			lines[i] = escapePropertyKey(key) + "=" + escapePropertyValue(desired)
+			changes = append(changes, propertyChange{Key: key, Old: value, New: desired})
+		}
+	}
+
+	// Drop the trailing empty line so appended keys follow the last property directly
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for _, key := range sortedKeys(values) {
+		if seen[key] {
+			continue
+		}
+		lines = append(lines, escapePropertyKey(key)+"="+escapePropertyValue(values[key]))
+		changes = append(changes, propertyChange{Key: key, New: values[key], Inserted: true})
+	}
+
+	return strings.Join(lines, "\n") + "\n", changes
+}
+
+// parsePropertyLine splits a properties line into its unescaped key and value
+func parsePropertyLine(line string) (string, string, bool) {
+	trimmed := strings.TrimLeft(line, " \t\f")
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+		return "", "", false
+	}
+
+	// The key ends at the first unescaped '=', ':' or whitespace
+	end := len(trimmed)
+	for i := 0; i < len(trimmed); i++ {
+		if trimmed[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.ContainsRune("=: \t\f", rune(trimmed[i])) {
+			end = i
+			break
+		}
+	}
+
+	key := trimmed[:end]
+	rest := strings.TrimLeft(trimmed[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	return unescapeProperty(key), unescapeProperty(strings.TrimRight(rest, "\r")), true
+}
+
+// unescapeProperty resolves the backslash escapes used in properties files
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			var r rune
+			if i+4 < len(s) {
+				if _, err := fmt.Sscanf(s[i+1:i+5], "%04x", &r); err == nil {
+					b.WriteRune(r)
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// escapePropertyKey escapes a key the way java.util.Properties writes it
+func escapePropertyKey(s string) string {
+	return escapeProperty(s, true)
+}
+
+// escapePropertyValue escapes a value the way java.util.Properties writes it
+func escapePropertyValue(s string) string {
+	return escapeProperty(s, false)
+}
+
+func escapeProperty(s string, isKey bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (isKey || i == 0):
+			b.WriteString(`\ `)
+		case strings.ContainsRune("=:#!", r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			if r > 0xffff || r == utf8.RuneError {
+				b.WriteRune(r)
+			} else {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
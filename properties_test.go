@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+// TestMergeProperties tests that managed values are merged without disturbing the rest of the file
+func TestMergeProperties(t *testing.T) {
+	content := `#Minecraft server properties
+#Sat Jan 04 12:00:00 CET 2025
+difficulty=easy
+motd=A Minecraft Server
+online-mode=true
+server-port=25565
+`
+	values := map[string]string{
+		"online-mode":      "false",
+		"server-port":      "25565",
+		"spawn-protection": "0",
+		"motd":             "Dev server: plugin test",
+	}
+
+	merged, changes := mergeProperties(content, values)
+
+	expected := `#Minecraft server properties
+#Sat Jan 04 12:00:00 CET 2025
+difficulty=easy
+motd=Dev server\: plugin test
+online-mode=false
+server-port=25565
+spawn-protection=0
+`
+	if merged != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, merged)
+	}
+
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d: %+v", len(changes), changes)
+	}
+	if changes[1].Key != "online-mode" || changes[1].Old != "true" || changes[1].New != "false" {
+		t.Errorf("Unexpected change: %+v", changes[1])
+	}
+	if !changes[2].Inserted || changes[2].Key != "spawn-protection" {
+		t.Errorf("Expected spawn-protection to be inserted, got %+v", changes[2])
+	}
+
+	t.Run("Unchanged values produce no changes", func(t *testing.T) {
+		again, changes := mergeProperties(merged, values)
+		if len(changes) != 0 || again != merged {
+			t.Errorf("Expected no changes on second merge, got %+v", changes)
+		}
+	})
+
+	t.Run("Escaped values are compared unescaped", func(t *testing.T) {
+		key, value, ok := parsePropertyLine(`level-name=worlds\\dev é`)
+		if !ok || key != "level-name" || value != `worlds\dev é` {
+			t.Errorf("Unexpected parse: %q=%q", key, value)
+		}
+		if escaped := escapePropertyValue(value); escaped != `worlds\\dev \u00E9` {
+			t.Errorf("Expected non-ASCII to be escaped, got %s", escaped)
+		}
+	})
+}
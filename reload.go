@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Reload strategies for applying plugin changes to a running server
+const (
+	reloadRestart  = "restart"   // Stop and start the server
+	reloadCommands = "commands"  // Run console commands, e.g. "plugman reload {plugin}"
+	reloadBukkit   = "reload"    // Run "reload confirm"
+	reloadCopyOnly = "copy-only" // Only copy the jar; the change is picked up on the next start
+)
+
+var reloadStrategyNames = []string{reloadRestart, reloadCommands, reloadBukkit, reloadCopyOnly}
+
+// reloadErrorWindow is how long the server output is checked for errors after a reload
+const reloadErrorWindow = 5 * time.Second
+
+// reloadErrorPattern matches server output showing that a reload went wrong: lines logged at
+// ERROR or SEVERE level, the start of a stack trace, and rejected commands
+var reloadErrorPattern = regexp.MustCompile(`(?i:[/ \[](?:ERROR|SEVERE)\]|Unknown or incomplete command|Unknown command)|^(?:Caused by: )?[\w$.]+Exception\b`)
+
+// PluginReload configures how changes to one plugin are applied to the running server
+type PluginReload struct {
+	Strategy string   `json:"strategy,omitempty"`
+	Commands []string `json:"commands,omitempty"` // For the "commands" strategy; {plugin} and {file} are replaced
+}
+
+// reloadSettingsFor returns the reload settings for a plugin: its pluginReload entry where
+// given, otherwise reloadStrategy and reloadCommands
+func reloadSettingsFor(pluginName string) PluginReload {
+	settings := PluginReload{Strategy: config.ReloadStrategy, Commands: config.ReloadCommands}
+	if override, ok := config.PluginReload[pluginName]; ok {
+		if override.Strategy != "" {
+			settings.Strategy = override.Strategy
+		}
+		if len(override.Commands) > 0 {
+			settings.Commands = override.Commands
+		}
+	}
+	if settings.Strategy == "" {
+		settings.Strategy = reloadRestart
+	}
+	return settings
+}
+
+// validateReloadSettings checks a reload strategy and its commands
+func validateReloadSettings(name string, settings PluginReload) error {
+	switch settings.Strategy {
+	case "", reloadRestart, reloadBukkit, reloadCopyOnly:
+	case reloadCommands:
+		if len(settings.Commands) == 0 {
+			return fmt.Errorf("%s uses the %q reload strategy but has no commands", name, reloadCommands)
+		}
+	default:
+		return fmt.Errorf("unknown reload strategy %q for %s (available: %s)",
+			settings.Strategy, name, strings.Join(reloadStrategyNames, ", "))
+	}
+	return nil
+}
+
+// applyPluginChanges brings deployed plugin changes into the running server using each
+// plugin's reload strategy. A restart covers the whole batch, so it wins over any other
+// strategy, and it is also the fallback when a reload shows errors.
+func applyPluginChanges(changes []*pluginChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	var commands []string
+	restart, bukkitReload := false, false
+	for _, change := range changes {
+		settings := reloadSettingsFor(change.Descriptor.Name)
+		switch {
+		case settings.Strategy == reloadCopyOnly:
+		case change.Kind == pluginRemoved:
+			// Reload commands can't bring back a plugin whose jar is gone
+			restart = true
+		case settings.Strategy == reloadCommands:
+			for _, command := range settings.Commands {
+				commands = append(commands, expandReloadCommand(command, change))
+			}
+		case settings.Strategy == reloadBukkit:
+			bukkitReload = true
+		default:
+			restart = true
+		}
+	}
+
+	// A server that is still starting may have loaded some of the old jars already
+	state := server.State()
+	if state == StateStarting && (len(commands) > 0 || bukkitReload) {
+		restart = true
+	}
+
+	if restart {
+		printWithPrompt("[Golem] Restarting server to apply plugin changes...")
+		restartWithLog()
+		return
+	}
+
+	if bukkitReload {
+		commands = append(commands, "reload confirm")
+	}
+	if len(commands) == 0 {
+		return
+	}
+	if state != StateRunning {
+		// Nothing to reload; the copied jars are loaded on the next start
+		return
+	}
+
+	if err := runReloadCommands(commands); err != nil {
+		printWithPrompt(fmt.Sprintf("[Golem] Reload failed (%v), restarting server instead...", err))
+		restartWithLog()
+		return
+	}
+	printWithPrompt("[Golem] Plugins reloaded")
+}
+
+// restartWithLog restarts the server, logging rather than returning a failure
+func restartWithLog() {
+	if err := server.Restart(); err != nil {
+		printWithPrompt(fmt.Sprintf("[Golem] Failed to restart server: %v", err))
+	}
+}
+
+// expandReloadCommand fills in the plugin name and jar file name of a reload command
+func expandReloadCommand(command string, change *pluginChange) string {
+	return strings.NewReplacer("{plugin}", change.Descriptor.Name, "{file}", change.Name).Replace(command)
+}
+
+// runReloadCommands sends commands to the server console and watches its output for errors
+// for reloadErrorWindow afterwards
+func runReloadCommands(commands []string) error {
+	output, unsubscribe := subscribeServerOutput()
+	defer unsubscribe()
+	states, unsubscribeStates := server.Subscribe()
+	defer unsubscribeStates()
+
+	for _, command := range commands {
+		printWithPrompt(fmt.Sprintf("[Golem] Running: %s", command))
+		if err := server.Send(command); err != nil {
+			return err
+		}
+	}
+
+	timeout := time.After(reloadErrorWindow)
+	for {
+		select {
+		case line := <-output:
+			if reloadErrorPattern.MatchString(line) {
+				return fmt.Errorf("server reported: %s", strings.TrimSpace(line))
+			}
+		case change := <-states:
+			return fmt.Errorf("server %s during reload", change.To)
+		case <-timeout:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReloadSettingsFor(t *testing.T) {
+	origConfig := config
+	defer func() { config = origConfig }()
+
+	config.ReloadStrategy = reloadCommands
+	config.ReloadCommands = []string{"plugman reload {plugin}"}
+	config.PluginReload = map[string]PluginReload{
+		"Core":  {Strategy: reloadRestart},
+		"Addon": {Commands: []string{"addon reload"}},
+	}
+
+	tests := map[string]PluginReload{
+		"Other": {Strategy: reloadCommands, Commands: []string{"plugman reload {plugin}"}},
+		"Core":  {Strategy: reloadRestart, Commands: []string{"plugman reload {plugin}"}},
+		"Addon": {Strategy: reloadCommands, Commands: []string{"addon reload"}},
+	}
+	for name, want := range tests {
+		if got := reloadSettingsFor(name); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %+v, got %+v", name, want, got)
+		}
+	}
+
+	change := &pluginChange{Name: "core-1.2.jar", Descriptor: &PluginDescriptor{Name: "Core"}}
+	if got := expandReloadCommand("plugman reload {plugin} # {file}", change); got != "plugman reload Core # core-1.2.jar" {
+		t.Errorf("Unexpected expanded command %q", got)
+	}
+}
+
+func TestValidateReloadSettings(t *testing.T) {
+	valid := []PluginReload{
+		{},
+		{Strategy: reloadRestart},
+		{Strategy: reloadBukkit},
+		{Strategy: reloadCopyOnly},
+		{Strategy: reloadCommands, Commands: []string{"plugman reload Core"}},
+	}
+	for _, settings := range valid {
+		if err := validateReloadSettings("test", settings); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", settings, err)
+		}
+	}
+
+	invalid := []PluginReload{
+		{Strategy: "hotswap"},
+		{Strategy: reloadCommands},
+	}
+	for _, settings := range invalid {
+		if err := validateReloadSettings("test", settings); err == nil {
+			t.Errorf("Expected %+v to be rejected", settings)
+		}
+	}
+}
+
+func TestReloadErrorPattern(t *testing.T) {
+	errors := []string{
+		"[12:00:01 ERROR]: Error occurred while enabling Core v1.0 (Is it up to date?)",
+		"[12:00:01] [Server thread/ERROR]: Could not load 'plugins/Core.jar'",
+		"[12:00:01 SEVERE]: Error occurred while enabling Core v1.0",
+		"java.lang.NullPointerException: Cannot invoke \"String.length()\"",
+		"Caused by: java.io.IOException: Stream closed",
+		"Unknown or incomplete command, see below for error",
+	}
+	for _, line := range errors {
+		if !reloadErrorPattern.MatchString(line) {
+			t.Errorf("Expected %q to count as a reload error", line)
+		}
+	}
+
+	fine := []string{
+		"[12:00:01 INFO]: [Core] Enabling Core v1.0",
+		"[12:00:01 INFO]: Core has been reloaded.",
+		"[12:00:01 INFO]: <Steve> why does my plugin throw an Exception",
+		"[12:00:01 WARN]: [Other] Handled java.io.IOException: Connection reset",
+	}
+	for _, line := range fine {
+		if reloadErrorPattern.MatchString(line) {
+			t.Errorf("Did not expect %q to count as a reload error", line)
+		}
+	}
+}
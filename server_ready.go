@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// compileReadyPatterns compiles the readyPatterns option, which matches the output line that
+// shows the server has finished starting
+func compileReadyPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid readyPatterns entry %q: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// isReadyLine reports whether a line of server output matches one of the ready patterns
+func isReadyLine(patterns []*regexp.Regexp, line string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
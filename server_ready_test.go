@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestReadyPatterns(t *testing.T) {
+	patterns, err := compileReadyPatterns(defaultConfig.ReadyPatterns)
+	if err != nil {
+		t.Fatalf("Default patterns should compile: %v", err)
+	}
+
+	tests := map[string]bool{
+		`[12:00:01 INFO]: Done (5.321s)! For help, type "help"`:                 true,
+		`[12:00:01] [Server thread/INFO]: Done (12,04s)! For help, type "help"`: true,
+		`[12:00:01 INFO]: Done (1.23s)!`:                                        true, // Velocity
+		`[12:00:00 INFO]: Preparing level "world"`:                              false,
+		`[12:00:02 INFO]: <Steve> Done (almost)!`:                               false,
+	}
+	for line, want := range tests {
+		if got := isReadyLine(patterns, line); got != want {
+			t.Errorf("isReadyLine(%q) = %v, expected %v", line, got, want)
+		}
+	}
+
+	if _, err := compileReadyPatterns([]string{"Done ("}); err == nil {
+		t.Errorf("Expected an invalid pattern to be rejected")
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerState is a stage in the life of the server process
+type ServerState int
+
+const (
+	StateStopped  ServerState = iota // Not running, or stopped on request
+	StateStarting                    // Launched, waiting for the ready line
+	StateRunning                     // Ready for players and console commands
+	StateStopping                    // Asked to stop, waiting for the process to exit
+	StateCrashed                     // Exited without being asked to
+)
+
+var serverStateNames = [...]string{"stopped", "starting", "running", "stopping", "crashed"}
+
+func (s ServerState) String() string {
+	return serverStateNames[s]
+}
+
+// StateChange is sent to subscribers on every state transition
+type StateChange struct {
+	From ServerState
+	To   ServerState
+}
+
+var errServerNotRunning = errors.New("server is not running")
+
+// Supervisor owns the server process. Starting, stopping, restarting and console commands
+// all go through it, and it is the only place that waits for the process to exit, so the
+// watcher, the console and the reload logic always agree on the server's state.
+type Supervisor struct {
+	launch       func() (*exec.Cmd, error) // Prepares the server command for each start
+	stopTimeout  time.Duration             // How long a stop may take before the process is killed
+	restartDelay time.Duration             // Pause between stopping and starting again
+
+	mu            sync.Mutex
+	state         ServerState
+	cmd           *exec.Cmd
+	stdin         io.WriteCloser
+	exited        chan struct{} // Closed when the current process has exited
+	readyPatterns []*regexp.Regexp
+	readyTimer    *time.Timer
+	startedAt     time.Time
+	launching     bool // Start is preparing the server, which can take a while
+	stopLaunch    bool // Stop was called while launching, so the server is not kept running
+	restartQueued bool // A restart was requested while starting
+	subscribers   map[chan StateChange]struct{}
+}
+
+// server supervises the Minecraft server Golem runs
+var server = newSupervisor(launchServer)
+
+func newSupervisor(launch func() (*exec.Cmd, error)) *Supervisor {
+	return &Supervisor{
+		launch:       launch,
+		stopTimeout:  30 * time.Second,
+		restartDelay: 3 * time.Second,
+		subscribers:  make(map[chan StateChange]struct{}),
+	}
+}
+
+// State returns the current state of the server
+func (s *Supervisor) State() ServerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Subscribe returns a channel receiving every state transition, and a function to stop
+// receiving them. Transitions are dropped if the subscriber falls behind.
+func (s *Supervisor) Subscribe() (<-chan StateChange, func()) {
+	ch := make(chan StateChange, 64)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+}
+
+// setState moves to a new state and notifies the subscribers; s.mu must be held
+func (s *Supervisor) setState(to ServerState) {
+	from := s.state
+	if from == to {
+		return
+	}
+	s.state = to
+	for ch := range s.subscribers {
+		select {
+		case ch <- StateChange{From: from, To: to}:
+		default:
+		}
+	}
+}
+
+// Start launches the server if it is stopped or has crashed. Preparing the launch can take
+// minutes, e.g. to download a Java runtime, so the process is started without holding the lock.
+func (s *Supervisor) Start() error {
+	patterns, err := compileReadyPatterns(config.ReadyPatterns)
+	if err != nil {
+		return err
+	}
+	readyTimeout, _ := time.ParseDuration(config.ReadyTimeout)
+
+	s.mu.Lock()
+	if s.launching {
+		s.mu.Unlock()
+		return errors.New("server is already being launched")
+	}
+	if state := s.state; state != StateStopped && state != StateCrashed {
+		s.mu.Unlock()
+		return fmt.Errorf("server is already %s", state)
+	}
+	s.launching, s.stopLaunch = true, false
+	s.mu.Unlock()
+
+	process, err := s.startProcess()
+
+	s.mu.Lock()
+	s.launching = false
+	if err != nil {
+		s.restartQueued = false
+		s.mu.Unlock()
+		return err
+	}
+	s.cmd, s.stdin, s.exited = process.cmd, process.stdin, make(chan struct{})
+	s.readyPatterns, s.startedAt = patterns, time.Now()
+	s.setState(StateStarting)
+	if readyTimeout > 0 {
+		s.readyTimer = time.AfterFunc(readyTimeout, func() {
+			s.markReady(fmt.Sprintf("No ready line from the server after %s", readyTimeout))
+		})
+	}
+	go s.wait(process.cmd, s.exited, process.stdout, process.stderr)
+	stopped := s.stopLaunch
+	s.mu.Unlock()
+	close(process.published)
+
+	// Stop was called after the process was started but before it was published
+	if stopped {
+		s.Stop()
+		return errors.New("server was stopped while being launched")
+	}
+	return nil
+}
+
+// startedProcess is a server process that Start has not published yet
+type startedProcess struct {
+	cmd            *exec.Cmd
+	stdin          io.WriteCloser
+	stdout, stderr *lineWriter
+	published      chan struct{} // Closed once the supervisor tracks the process
+}
+
+// startProcess gets the server command from launch and starts it, unless Stop was called in
+// the meantime. Its output is held back until it is published, so no ready line is missed.
+func (s *Supervisor) startProcess() (*startedProcess, error) {
+	cmd, err := s.launch()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	stopped := s.stopLaunch
+	s.mu.Unlock()
+	if stopped {
+		return nil, errors.New("server was stopped while being launched")
+	}
+
+	published := make(chan struct{})
+	stdout := &lineWriter{gate: published, handle: func(line string) { s.handleOutput(line, false) }}
+	stderr := &lineWriter{gate: published, handle: func(line string) { s.handleOutput(line, true) }}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Don't wait forever for output from processes the server left behind
+	cmd.WaitDelay = 5 * time.Second
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start server: %v", err)
+	}
+	log.Printf("Server started with PID %d", cmd.Process.Pid)
+	return &startedProcess{cmd: cmd, stdin: stdin, stdout: stdout, stderr: stderr, published: published}, nil
+}
+
+// wait waits for a server process to exit and records how it ended
+func (s *Supervisor) wait(cmd *exec.Cmd, exited chan struct{}, stdout, stderr *lineWriter) {
+	err := cmd.Wait()
+	stdout.Flush()
+	stderr.Flush()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		log.Println("Server stopped gracefully")
+	case errors.As(err, &exitErr):
+		log.Printf("Server exited with code %d", exitErr.ExitCode())
+	default:
+		log.Printf("Server exited with error: %v", err)
+	}
+
+	s.mu.Lock()
+	if s.readyTimer != nil {
+		s.readyTimer.Stop()
+	}
+	s.cmd, s.stdin = nil, nil
+	close(exited)
+
+	// A server that stops by itself without an error was stopped from its own console
+	if s.state == StateStopping || err == nil {
+		s.setState(StateStopped)
+	} else {
+		s.setState(StateCrashed)
+	}
+	queued := s.restartQueued
+	s.restartQueued = false
+	s.mu.Unlock()
+
+	if queued {
+		// The restart was meant to bring in changes, which may well fix the crash
+		go s.runQueuedRestart()
+	}
+}
+
+// handleOutput shows a line of server output, passes it to the output subscribers and checks
+// whether it shows that the server is ready
+func (s *Supervisor) handleOutput(line string, stderr bool) {
+	if stderr {
+		printWithPrompt(fmt.Sprintf("[Server Error] %s", line))
+	} else {
+		printWithPrompt(fmt.Sprintf("[Server] %s", line))
+	}
+	publishServerOutput(line)
+
+	s.mu.Lock()
+	starting, patterns := s.state == StateStarting, s.readyPatterns
+	s.mu.Unlock()
+	if starting && isReadyLine(patterns, line) {
+		s.markReady("")
+	}
+}
+
+// markReady moves a starting server to running, and runs a restart that was queued while it
+// was starting. reason explains why the server counts as ready without a ready line.
+func (s *Supervisor) markReady(reason string) {
+	s.mu.Lock()
+	if s.state != StateStarting {
+		s.mu.Unlock()
+		return
+	}
+	if s.readyTimer != nil {
+		s.readyTimer.Stop()
+	}
+	s.setState(StateRunning)
+	queued := s.restartQueued
+	s.restartQueued = false
+	elapsed := time.Since(s.startedAt).Round(100 * time.Millisecond)
+	s.mu.Unlock()
+
+	if reason != "" {
+		printWithPrompt(fmt.Sprintf("[Golem] %s, treating the server as ready", reason))
+	} else {
+		printWithPrompt(fmt.Sprintf("[Golem] Server is ready (started in %s)", elapsed))
+	}
+
+	// Restarting waits for the process to exit, which waits for this output handler
+	if queued {
+		go s.runQueuedRestart()
+	}
+}
+
+func (s *Supervisor) runQueuedRestart() {
+	printWithPrompt("[Golem] Running the restart that was queued during startup...")
+	if err := s.Restart(); err != nil {
+		printWithPrompt(fmt.Sprintf("[Golem] Failed to restart server: %v", err))
+	}
+}
+
+// Stop asks the server to stop and waits for it to exit, killing it after stopTimeout.
+// It also drops a restart that was queued during startup.
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	s.restartQueued = false
+	switch s.state {
+	case StateStopped, StateCrashed:
+		// A server that is still being launched is not kept running
+		s.stopLaunch = s.launching
+		s.mu.Unlock()
+		return nil
+	case StateStopping:
+		// Someone else is stopping it already
+		exited := s.exited
+		s.mu.Unlock()
+		<-exited
+		return nil
+	}
+
+	log.Println("Stopping server gracefully...")
+	s.setState(StateStopping)
+	stdin, process, exited := s.stdin, s.cmd.Process, s.exited
+	s.mu.Unlock()
+
+	// A hung server may not read its input, so don't let the write hold up the kill
+	go func() {
+		if _, err := stdin.Write([]byte("stop\n")); err != nil {
+			log.Printf("Failed to send stop command (%v), killing the server", err)
+			process.Kill()
+		}
+	}()
+
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(s.stopTimeout):
+		// Force kill if server doesn't stop gracefully
+		if err := process.Kill(); err != nil {
+			return fmt.Errorf("failed to force kill server: %v", err)
+		}
+		<-exited
+		return fmt.Errorf("server did not stop gracefully within %s", s.stopTimeout)
+	}
+}
+
+// Restart stops the server if it is running and starts it again. Stopping a server that is
+// still starting can corrupt worlds, so then the restart is queued until it is ready.
+func (s *Supervisor) Restart() error {
+	s.mu.Lock()
+	state := s.state
+	if state == StateStarting || s.launching {
+		if !s.restartQueued {
+			printWithPrompt("[Golem] Server is still starting, restarting once it is ready")
+		}
+		s.restartQueued = true
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	if state == StateRunning || state == StateStopping {
+		log.Println("Stopping server before restart...")
+		if err := s.Stop(); err != nil {
+			log.Printf("Warning during server stop: %v", err)
+		}
+
+		// Wait a bit for the server to fully stop and resources to be released
+		log.Printf("Waiting %s before restart...", s.restartDelay)
+		time.Sleep(s.restartDelay)
+	} else {
+		log.Println("No server process found to stop, starting fresh")
+	}
+
+	log.Println("Starting server...")
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("failed to restart server: %v", err)
+	}
+	return nil
+}
+
+// Send writes a command to the server console. The write happens without holding the lock,
+// so a server that stopped reading its input can still be stopped.
+func (s *Supervisor) Send(command string) error {
+	s.mu.Lock()
+	if s.state != StateStarting && s.state != StateRunning {
+		s.mu.Unlock()
+		return errServerNotRunning
+	}
+	stdin := s.stdin
+	s.mu.Unlock()
+
+	if _, err := stdin.Write([]byte(command + "\n")); err != nil {
+		return fmt.Errorf("failed to send command: %v", err)
+	}
+	return nil
+}
+
+// lineWriter passes each complete line written to it to handle, once gate is closed
+type lineWriter struct {
+	buf    []byte
+	gate   <-chan struct{}
+	handle func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		w.handle(line)
+	}
+	return len(p), nil
+}
+
+// Flush passes on a last line that did not end with a newline
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.handle(strings.TrimSuffix(string(w.buf), "\r"))
+		w.buf = nil
+	}
+}
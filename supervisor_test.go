@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary stand in for a Minecraft server, see runFakeServer
+func TestMain(m *testing.M) {
+	if os.Getenv("GOLEM_FAKE_SERVER") == "1" {
+		runFakeServer()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeServer behaves like a Minecraft server on the console: it prints a ready line after
+// GOLEM_FAKE_READY_DELAY, echoes "say" commands, exits with an error on "crash" and stops on
+// "stop" unless GOLEM_FAKE_IGNORE_STOP is set. With GOLEM_FAKE_HUNG it hangs once ready.
+func runFakeServer() {
+	fmt.Println(`[12:00:00 INFO]: Starting minecraft server version 1.21`)
+	if delay, err := time.ParseDuration(os.Getenv("GOLEM_FAKE_READY_DELAY")); err == nil {
+		time.Sleep(delay)
+	}
+	fmt.Println(`[12:00:01 INFO]: Done (0.123s)! For help, type "help"`)
+	if os.Getenv("GOLEM_FAKE_HUNG") != "" {
+		// Stop reading the console, so writes to it block once the pipe is full
+		time.Sleep(time.Hour)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		command := scanner.Text()
+		switch {
+		case command == "stop" && os.Getenv("GOLEM_FAKE_IGNORE_STOP") == "":
+			fmt.Println("[12:00:02 INFO]: Stopping server")
+			os.Exit(0)
+		case command == "crash":
+			fmt.Fprintln(os.Stderr, "java.lang.IllegalStateException: crashed")
+			os.Exit(1)
+		case strings.HasPrefix(command, "say "):
+			fmt.Println("[12:00:02 INFO]: [Server] " + strings.TrimPrefix(command, "say "))
+		}
+	}
+	os.Exit(0)
+}
+
+// newFakeSupervisor returns a supervisor running the fake server with extra environment variables
+func newFakeSupervisor(t *testing.T, env ...string) *Supervisor {
+	t.Helper()
+	origConfig := config
+	config.ReadyPatterns = defaultConfig.ReadyPatterns
+	config.ReadyTimeout = ""
+	t.Cleanup(func() { config = origConfig })
+
+	s := newSupervisor(func() (*exec.Cmd, error) {
+		cmd := exec.Command(os.Args[0])
+		cmd.Env = append(append(os.Environ(), "GOLEM_FAKE_SERVER=1"), env...)
+		return cmd, nil
+	})
+	s.restartDelay = 0
+	t.Cleanup(func() { s.Stop() })
+	return s
+}
+
+// expectStates waits for the given sequence of states on a subscription
+func expectStates(t *testing.T, changes <-chan StateChange, states ...ServerState) {
+	t.Helper()
+	for _, want := range states {
+		select {
+		case change := <-changes:
+			if change.To != want {
+				t.Fatalf("Expected a transition to %s, got %s → %s", want, change.From, change.To)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for the server to become %s", want)
+		}
+	}
+}
+
+func TestSupervisorLifecycle(t *testing.T) {
+	s := newFakeSupervisor(t)
+	changes, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	if err := s.Send("say hi"); err != errServerNotRunning {
+		t.Errorf("Expected sending to a stopped server to fail, got %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	if err := s.Start(); err == nil {
+		t.Errorf("Starting a server that is already starting should fail")
+	}
+	expectStates(t, changes, StateStarting, StateRunning)
+
+	// Console commands reach the server and its output comes back
+	output, unsubscribeOutput := subscribeServerOutput()
+	defer unsubscribeOutput()
+	if err := s.Send("say hello"); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	for line := range output {
+		if strings.Contains(line, "[Server] hello") {
+			break
+		}
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+	expectStates(t, changes, StateStopping, StateStopped)
+	if s.State() != StateStopped {
+		t.Errorf("Expected the server to be stopped, got %s", s.State())
+	}
+}
+
+func TestSupervisorCrash(t *testing.T) {
+	s := newFakeSupervisor(t)
+	changes, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	expectStates(t, changes, StateStarting, StateRunning)
+	if err := s.Send("crash"); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	expectStates(t, changes, StateCrashed)
+
+	// A crashed server can be started again
+	if err := s.Restart(); err != nil {
+		t.Fatalf("Failed to restart: %v", err)
+	}
+	expectStates(t, changes, StateStarting, StateRunning)
+}
+
+func TestSupervisorQueuesRestartDuringStartup(t *testing.T) {
+	s := newFakeSupervisor(t, "GOLEM_FAKE_READY_DELAY=500ms")
+	changes, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	expectStates(t, changes, StateStarting)
+
+	// Restarting returns straight away and happens once the server is ready
+	if err := s.Restart(); err != nil {
+		t.Fatalf("Failed to queue restart: %v", err)
+	}
+	if err := s.Restart(); err != nil {
+		t.Fatalf("Failed to queue restart: %v", err)
+	}
+	if s.State() != StateStarting {
+		t.Errorf("A server that is starting should not be stopped, got %s", s.State())
+	}
+	expectStates(t, changes, StateRunning, StateStopping, StateStopped, StateStarting, StateRunning)
+
+	// Both requests were served by one restart
+	select {
+	case change := <-changes:
+		t.Errorf("Unexpected transition %s → %s", change.From, change.To)
+	case <-time.After(700 * time.Millisecond):
+	}
+}
+
+func TestSupervisorStopTimeout(t *testing.T) {
+	s := newFakeSupervisor(t, "GOLEM_FAKE_IGNORE_STOP=1")
+	s.stopTimeout = 200 * time.Millisecond
+	changes, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	expectStates(t, changes, StateStarting, StateRunning)
+
+	// Concurrent stops wait for the same process
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.Stop()
+		}(i)
+	}
+	wg.Wait()
+
+	if errs[0] == nil && errs[1] == nil {
+		t.Errorf("Expected the stop that killed the server to report it")
+	}
+	if s.State() != StateStopped {
+		t.Errorf("A killed server that was asked to stop should be stopped, got %s", s.State())
+	}
+}
+
+func TestSupervisorLaunchDoesNotHoldLock(t *testing.T) {
+	// launchAfter makes the supervisor's first launch wait until the returned channel is closed
+	launchAfter := func(s *Supervisor) (launching, release chan struct{}) {
+		launching, release = make(chan struct{}), make(chan struct{})
+		launch := s.launch
+		var once sync.Once
+		s.launch = func() (*exec.Cmd, error) {
+			once.Do(func() {
+				close(launching)
+				<-release
+			})
+			return launch()
+		}
+		return launching, release
+	}
+
+	t.Run("restart is queued", func(t *testing.T) {
+		s := newFakeSupervisor(t)
+		launching, release := launchAfter(s)
+		changes, unsubscribe := s.Subscribe()
+		defer unsubscribe()
+
+		started := make(chan error, 1)
+		go func() { started <- s.Start() }()
+		<-launching
+
+		// The supervisor keeps answering while the launch is being prepared
+		if state := s.State(); state != StateStopped {
+			t.Errorf("Expected the server to be stopped while launching, got %s", state)
+		}
+		if err := s.Send("say hi"); err != errServerNotRunning {
+			t.Errorf("Expected sending while launching to fail, got %v", err)
+		}
+		if err := s.Start(); err == nil {
+			t.Errorf("Starting a server that is being launched should fail")
+		}
+		if err := s.Restart(); err != nil {
+			t.Errorf("Failed to queue restart: %v", err)
+		}
+
+		close(release)
+		if err := <-started; err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		expectStates(t, changes, StateStarting, StateRunning, StateStopping, StateStopped, StateStarting, StateRunning)
+	})
+
+	t.Run("stop cancels the launch", func(t *testing.T) {
+		s := newFakeSupervisor(t)
+		launching, release := launchAfter(s)
+
+		started := make(chan error, 1)
+		go func() { started <- s.Start() }()
+		<-launching
+
+		if err := s.Stop(); err != nil {
+			t.Errorf("Failed to stop: %v", err)
+		}
+		close(release)
+		if err := <-started; err == nil {
+			t.Errorf("Expected the stopped launch to report it")
+		}
+		if state := s.State(); state != StateStopped {
+			t.Errorf("Expected the server to stay stopped, got %s", state)
+		}
+	})
+}
+
+func TestSupervisorStopsHungServer(t *testing.T) {
+	s := newFakeSupervisor(t, "GOLEM_FAKE_HUNG=1")
+	s.stopTimeout = 200 * time.Millisecond
+	changes, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	expectStates(t, changes, StateStarting, StateRunning)
+
+	// A command larger than the pipe buffer blocks, as the server doesn't read it
+	sent := make(chan error, 1)
+	go func() { sent <- s.Send("say " + strings.Repeat("x", 1<<20)) }()
+	select {
+	case err := <-sent:
+		t.Fatalf("Expected the command to block, got %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop() }()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Stop was blocked by the pending command")
+	}
+	if state := s.State(); state != StateStopped {
+		t.Errorf("Expected the killed server to be stopped, got %s", state)
+	}
+	if err := <-sent; err == nil {
+		t.Errorf("Expected the pending command to fail once the server was killed")
+	}
+}
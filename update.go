@@ -50,7 +50,7 @@ func updateServer() error {
 
 	// Update server based on type
 	switch config.ServerType {
-	case Paper:
+	case Paper, Folia:
 		return updatePaper()
 	case Purpur:
 		return updatePurpur()
@@ -12,13 +12,12 @@ func updatePaper() error {
 	projectName := strings.ToLower(string(config.ServerType))
 
 	// Get latest version
-	versionsURL := fmt.Sprintf("https://api.papermc.io/v2/projects/%s", projectName)
-	versions := &PaperAPIVersions{}
-	if err := fetchJSON(versionsURL, versions); err != nil {
+	versions, err := fetchPaperVersions(projectName)
+	if err != nil {
 		return fmt.Errorf("failed to fetch versions: %v", err)
 	}
 
-	latestVersion := versions.Versions[len(versions.Versions)-1]
+	latestVersion := versions[len(versions)-1]
 	currentVersion := config.ServerVersion
 
 	// Check if major version update is needed
@@ -29,17 +28,16 @@ func updatePaper() error {
 	}
 
 	// Get latest build
-	buildsURL := fmt.Sprintf("https://api.papermc.io/v2/projects/%s/versions/%s/builds", projectName, currentVersion)
-	log.Printf("Fetching builds... (build number: %d, fullURL: %s)", config.BuildNumber, buildsURL)
-	builds := &PaperAPIBuilds{}
-	if err := fetchJSON(buildsURL, builds); err != nil {
+	log.Printf("Fetching builds... (build number: %d)", config.BuildNumber)
+	builds, err := fetchPaperBuilds(projectName, currentVersion)
+	if err != nil {
 		return fmt.Errorf("failed to fetch builds: %v", err)
 	}
 
 	var latestBuild int
 	for i := len(builds.Builds) - 1; i >= 0; i-- {
 		build := builds.Builds[i]
-		if config.AllowExperimentalBuilds || args.Watch != "" || build.Channel == "default" {
+		if config.AllowExperimentalBuilds || config.Watch != "" || build.Channel == "default" {
 			latestBuild = build.Build
 			break
 		}
@@ -117,10 +115,33 @@ func updatePaper() error {
 
 	// Update build number in config
 	config.BuildNumber = latestBuild
-	if err := saveConfig(args.Config); err != nil {
+	if err := saveBuildNumber(latestBuild); err != nil {
 		return fmt.Errorf("failed to save config: %v", err)
 	}
 
 	log.Printf("Successfully updated to build %d", latestBuild)
-	return acceptEULA()
+	return nil
+}
+
+// fetchPaperVersions returns the versions published for a PaperMC project, oldest first
+func fetchPaperVersions(projectName string) ([]string, error) {
+	versionsURL := fmt.Sprintf("https://api.papermc.io/v2/projects/%s", projectName)
+	versions := &PaperAPIVersions{}
+	if err := fetchJSON(versionsURL, versions); err != nil {
+		return nil, err
+	}
+	if len(versions.Versions) == 0 {
+		return nil, fmt.Errorf("no versions published for %s", projectName)
+	}
+	return versions.Versions, nil
+}
+
+// fetchPaperBuilds returns every build of a PaperMC project version, oldest first
+func fetchPaperBuilds(projectName, version string) (*PaperAPIBuilds, error) {
+	buildsURL := fmt.Sprintf("https://api.papermc.io/v2/projects/%s/versions/%s/builds", projectName, version)
+	builds := &PaperAPIBuilds{}
+	if err := fetchJSON(buildsURL, builds); err != nil {
+		return nil, err
+	}
+	return builds, nil
 }
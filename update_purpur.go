@@ -8,6 +8,11 @@ import (
 	"strings"
 )
 
+type PurpurAPIProject struct {
+	Project  string   `json:"project"`
+	Versions []string `json:"versions"`
+}
+
 type PurpurAPIResponse struct {
 	Project   string `json:"project"`
 	Version   string `json:"version"`
@@ -92,7 +97,7 @@ func updatePurpur() error {
 
 		// Update build number in config
 		config.BuildNumber = parseInt(latestBuildNum)
-		if err := saveConfig(args.Config); err != nil {
+		if err := saveBuildNumber(config.BuildNumber); err != nil {
 			return fmt.Errorf("failed to save config: %v", err)
 		}
 
@@ -101,7 +106,19 @@ func updatePurpur() error {
 		log.Printf("Already on latest build %d with correct checksum", config.BuildNumber)
 	}
 
-	return acceptEULA()
+	return nil
+}
+
+// fetchPurpurVersions returns the Minecraft versions Purpur publishes builds for, oldest first
+func fetchPurpurVersions() ([]string, error) {
+	project := &PurpurAPIProject{}
+	if err := fetchJSON("https://api.purpurmc.org/v2/purpur", project); err != nil {
+		return nil, err
+	}
+	if len(project.Versions) == 0 {
+		return nil, fmt.Errorf("no versions published for purpur")
+	}
+	return project.Versions, nil
 }
 
 func parseInt(s string) int {
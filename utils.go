@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
@@ -10,8 +11,12 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/chzyer/readline"
 )
 
 var p *tea.Program
@@ -49,33 +54,70 @@ func (e progressErrMsg) Error() string {
 	return e.err.Error()
 }
 
+const eulaURL = "https://aka.ms/MinecraftEULA"
+
+var eulaLinePattern = regexp.MustCompile(`(?m)^\s*eula\s*=\s*(\S*)\s*$`)
+
+// acceptEULA makes sure the Minecraft EULA has been agreed to before the server starts.
+// An eula.txt that already says eula=true is left untouched; otherwise consent comes from
+// acceptEula in the config (or --accept-eula), or from asking on an interactive terminal
+// before the console has started. The answer is remembered by writing eula=true to the
+// server's eula.txt, deliberately replacing the eula=false line a server writes on its
+// first run.
 func acceptEULA() error {
 	eulaPath := filepath.Join(config.ServerPath, "eula.txt")
-	content := "eula=true"
-	if err := os.WriteFile(eulaPath, []byte(content), 0644); err != nil {
+	data, err := os.ReadFile(eulaPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read eula.txt: %v", err)
+	}
+
+	if match := eulaLinePattern.FindSubmatch(data); match != nil && strings.EqualFold(string(match[1]), "true") {
+		return nil
+	}
+
+	switch {
+	case config.AcceptEULA:
+		log.Printf("Accepting the Minecraft EULA (%s) as set by acceptEula", eulaURL)
+	case readline.IsTerminal(int(os.Stdin.Fd())) && !consoleStarted.Load():
+		// Once the console reads the terminal a second reader would swallow its input
+		if !askEULAConsent() {
+			return fmt.Errorf("the Minecraft EULA (%s) must be accepted to run the server", eulaURL)
+		}
+	default:
+		return fmt.Errorf("the Minecraft EULA (%s) has not been accepted for %s; "+
+			"set acceptEula: true in the config or pass --accept-eula", eulaURL, config.ServerPath)
+	}
+
+	// Keep the rest of an existing eula.txt, such as the comments the server writes
+	var content []byte
+	if eulaLinePattern.Match(data) {
+		content = eulaLinePattern.ReplaceAll(data, []byte("eula=true"))
+	} else {
+		content = []byte(fmt.Sprintf("#By changing the setting below to TRUE you are indicating your agreement to our EULA (%s).\n"+
+			"#Accepted through Golem on %s\n", eulaURL, time.Now().Format(time.RFC1123)))
+		content = append(append(content, data...), "eula=true\n"...)
+	}
+
+	if err := os.MkdirAll(config.ServerPath, 0755); err != nil {
+		return fmt.Errorf("failed to create server directory: %v", err)
+	}
+	if err := os.WriteFile(eulaPath, content, 0644); err != nil {
 		return fmt.Errorf("failed to write eula.txt: %v", err)
 	}
-	log.Println("By using this configuration, you are indicating your agreement to Minecraft's EULA.")
-	log.Println("See https://www.minecraft.net/en-us/eula for more information.")
-	log.Println("If you do not agree to the EULA above, please stop the server.")
 	return nil
 }
 
-func copyFile(src, dst string) error {
-	source, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer source.Close()
+// askEULAConsent asks on the terminal whether the user agrees to the Minecraft EULA
+func askEULAConsent() bool {
+	fmt.Printf("The Minecraft server requires you to agree to the Minecraft EULA: %s\n", eulaURL)
+	fmt.Print("Do you agree to the EULA? [y/N]: ")
 
-	destination, err := os.Create(dst)
-	if err != nil {
-		return err
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return false
 	}
-	defer destination.Close()
-
-	_, err = io.Copy(destination, source)
-	return err
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
 }
 
 func fetchJSON(url string, target interface{}) error {
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// pollInterval is how often watched directories are rescanned when events are unavailable
+	pollInterval = 2 * time.Second
+	// eventSettleDelay groups the burst of events a single jar write produces into one check
+	eventSettleDelay = 300 * time.Millisecond
+)
+
+// changeNotifier signals on C whenever matching files below its root directories may have
+// changed. It uses filesystem events where the platform supports them and falls back to polling.
+type changeNotifier struct {
+	C       chan struct{}
+	done    chan struct{}
+	watcher *fsnotify.Watcher
+	roots   []WatchPath // Only Path and Recursive are used, match does the filtering
+	match   func(path string) bool
+}
+
+// fileStamp is what polling and the hash cache compare to notice that a file changed
+type fileStamp struct {
+	Size    int64
+	ModTime time.Time
+}
+
+func stampOf(info os.FileInfo) fileStamp {
+	return fileStamp{Size: info.Size(), ModTime: info.ModTime()}
+}
+
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.Size == other.Size && s.ModTime.Equal(other.ModTime)
+}
+
+// isJarFile matches plugin jars
+func isJarFile(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".jar")
+}
+
+// newChangeNotifier starts watching the files matched by match in the root directories,
+// and in all their subdirectories for the roots that are recursive
+func newChangeNotifier(roots []WatchPath, match func(path string) bool) *changeNotifier {
+	n := &changeNotifier{
+		C:     make(chan struct{}, 1),
+		done:  make(chan struct{}),
+		match: match,
+	}
+	var paths []string
+	for _, root := range roots {
+		paths = append(paths, root.Path)
+		if absRoot, err := filepath.Abs(root.Path); err == nil {
+			root.Path = absRoot
+		}
+		n.roots = append(n.roots, root)
+	}
+
+	description := strings.Join(paths, ", ")
+	if config.WatchPolling {
+		log.Printf("Polling %s every %s for changes", description, pollInterval)
+	} else if err := n.startEvents(); err != nil {
+		log.Printf("Filesystem events are unavailable for %s (%v), polling every %s instead", description, err, pollInterval)
+	} else {
+		return n
+	}
+
+	go n.poll()
+	return n
+}
+
+// startEvents subscribes to filesystem events for the roots
+func (n *changeNotifier) startEvents() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	n.watcher = watcher
+
+	for _, root := range n.roots {
+		if err := n.watchTowards(root); err != nil {
+			watcher.Close()
+			n.watcher = nil
+			return err
+		}
+	}
+
+	go n.run()
+	return nil
+}
+
+// watchTowards watches a root if it exists, along with its parent so that it is noticed
+// when a clean build deletes and recreates it. A root that does not exist yet, like
+// build/libs before the first build, is waited for by watching its closest existing ancestor.
+func (n *changeNotifier) watchTowards(root WatchPath) error {
+	dir := root.Path
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no existing directory above %s", root.Path)
+		}
+		dir = parent
+	}
+
+	if dir != root.Path {
+		return n.watcher.Add(dir)
+	}
+	if err := n.addDir(root.Path, root.Recursive); err != nil {
+		return err
+	}
+	if err := n.watcher.Add(filepath.Dir(root.Path)); err != nil {
+		log.Printf("Warning: cannot watch %s, a recreated %s will not be noticed: %v",
+			filepath.Dir(root.Path), filepath.Base(root.Path), err)
+	}
+	return nil
+}
+
+// addDir watches a directory, and its subdirectories when recursive is set
+func (n *changeNotifier) addDir(dir string, recursive bool) error {
+	if !recursive {
+		return n.watcher.Add(dir)
+	}
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return n.watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// relevant reports whether an event can affect the watched files, and watches directories
+// that were just created below or above a root
+func (n *changeNotifier) relevant(event fsnotify.Event) bool {
+	if n.followRoots(event) {
+		return true
+	}
+
+	for _, root := range n.roots {
+		if !strings.HasPrefix(event.Name, root.Path+string(os.PathSeparator)) {
+			continue
+		}
+		if !root.Recursive {
+			// Another root may still cover the subdirectory the event happened in
+			if filepath.Dir(event.Name) == root.Path && n.match(event.Name) {
+				return true
+			}
+			continue
+		}
+		if event.Has(fsnotify.Create) {
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				// Files may have been written before the new directory was watched
+				if err := n.addDir(event.Name, true); err != nil {
+					log.Printf("Failed to watch new directory %s: %v", event.Name, err)
+				}
+				return true
+			}
+		}
+		return n.match(event.Name) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+	}
+	return false
+}
+
+// followRoots re-watches every root that an event created or removed, directly or through
+// one of its parents. A created root may already hold files by the time it is watched, so
+// such events always count as a change.
+func (n *changeNotifier) followRoots(event fsnotify.Event) bool {
+	if event.Has(fsnotify.Write) || event.Has(fsnotify.Chmod) {
+		return false
+	}
+
+	followed := false
+	for _, root := range n.roots {
+		if event.Name != root.Path && !strings.HasPrefix(root.Path, event.Name+string(os.PathSeparator)) {
+			continue
+		}
+		if err := n.watchTowards(root); err != nil {
+			log.Printf("Failed to watch %s: %v", root.Path, err)
+		}
+		followed = true
+	}
+	return followed
+}
+
+// run turns relevant filesystem events into change signals, once they have settled
+func (n *changeNotifier) run() {
+	settle := time.NewTimer(eventSettleDelay)
+	settle.Stop()
+
+	for {
+		select {
+		case event, ok := <-n.watcher.Events:
+			if !ok {
+				return
+			}
+			if n.relevant(event) {
+				settle.Reset(eventSettleDelay)
+			}
+
+		case err, ok := <-n.watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been dropped, so rescan to be safe
+			log.Printf("Watch error: %v", err)
+			settle.Reset(eventSettleDelay)
+
+		case <-settle.C:
+			n.signal()
+
+		case <-n.done:
+			settle.Stop()
+			return
+		}
+	}
+}
+
+// poll rescans the roots on every tick for filesystems without event support, and signals
+// when a matching file was added, changed or removed
+func (n *changeNotifier) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	last := n.snapshot()
+	for {
+		select {
+		case <-ticker.C:
+			current := n.snapshot()
+			if !sameSnapshot(last, current) {
+				n.signal()
+			}
+			last = current
+		case <-n.done:
+			return
+		}
+	}
+}
+
+// snapshot records the size and mtime of every matching file below the roots
+func (n *changeNotifier) snapshot() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, root := range n.roots {
+		filepath.WalkDir(root.Path, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if entry.IsDir() {
+				if path != root.Path && !root.Recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !n.match(path) {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				stamps[path] = stampOf(info)
+			}
+			return nil
+		})
+	}
+	return stamps
+}
+
+func sameSnapshot(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		if other, ok := b[path]; !ok || !other.equal(stamp) {
+			return false
+		}
+	}
+	return true
+}
+
+// signal notifies the listener without blocking; one pending signal is enough to trigger a scan
+func (n *changeNotifier) signal() {
+	select {
+	case n.C <- struct{}{}:
+	default:
+	}
+}
+
+// recheckAfter signals again once d has passed, for changes that were not ready yet
+func (n *changeNotifier) recheckAfter(d time.Duration) {
+	time.AfterFunc(d, func() {
+		select {
+		case <-n.done:
+		default:
+			n.signal()
+		}
+	})
+}
+
+// Close stops watching
+func (n *changeNotifier) Close() {
+	close(n.done)
+	if n.watcher != nil {
+		n.watcher.Close()
+	}
+}
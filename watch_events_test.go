@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestChangeNotifier checks that jar writes are signalled and unrelated files are ignored
+func TestChangeNotifier(t *testing.T) {
+	dir := t.TempDir()
+	config = Config{}
+
+	notifier := newChangeNotifier([]WatchPath{{Path: dir}}, isJarFile)
+	defer notifier.Close()
+	if notifier.watcher == nil {
+		t.Skip("filesystem events are not available here")
+	}
+
+	expectSignal := func(want bool) {
+		t.Helper()
+		select {
+		case <-notifier.C:
+			if !want {
+				t.Errorf("Unexpected change signal")
+			}
+		case <-time.After(eventSettleDelay + time.Second):
+			if want {
+				t.Errorf("Expected a change signal")
+			}
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectSignal(false)
+
+	// Several writes in a row produce a single signal
+	jarPath := filepath.Join(dir, "Plugin.jar")
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(jarPath, []byte{byte(i)}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectSignal(true)
+	expectSignal(false)
+
+	// A directory deleted and recreated by a clean build is watched again
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	expectSignal(true)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	expectSignal(true)
+	if err := os.WriteFile(jarPath, []byte("rebuilt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectSignal(true)
+}
+
+// TestChangeNotifierMissingRoot checks that an output directory created by the first build is picked up
+func TestChangeNotifierMissingRoot(t *testing.T) {
+	project := t.TempDir()
+	root := filepath.Join(project, "build", "libs")
+	config = Config{}
+
+	notifier := newChangeNotifier([]WatchPath{{Path: root}}, isJarFile)
+	defer notifier.Close()
+	if notifier.watcher == nil {
+		t.Skip("filesystem events are not available here")
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-notifier.C:
+	case <-time.After(eventSettleDelay + time.Second):
+		t.Fatalf("Expected a signal when the output directory was created")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "Plugin.jar"), []byte("built"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-notifier.C:
+	case <-time.After(eventSettleDelay + time.Second):
+		t.Errorf("Expected a signal for a jar in the new output directory")
+	}
+}
+
+// TestChangeNotifierPerRootRecursion checks that only recursive roots get their subdirectories watched
+func TestChangeNotifierPerRootRecursion(t *testing.T) {
+	project := t.TempDir()
+	libs := filepath.Join(project, "libs")
+	for _, dir := range []string{filepath.Join(project, ".gradle", "caches"), filepath.Join(libs, "nested")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config = Config{}
+
+	notifier := newChangeNotifier([]WatchPath{{Path: project}, {Path: libs, Recursive: true}}, isJarFile)
+	defer notifier.Close()
+	if notifier.watcher == nil {
+		t.Skip("filesystem events are not available here")
+	}
+
+	watched := make(map[string]bool)
+	for _, dir := range notifier.watcher.WatchList() {
+		watched[dir] = true
+	}
+	if !watched[filepath.Join(libs, "nested")] {
+		t.Errorf("Expected the subdirectory of the recursive root to be watched")
+	}
+	if watched[filepath.Join(project, ".gradle")] || watched[filepath.Join(project, ".gradle", "caches")] {
+		t.Errorf("Subdirectories of the non-recursive root should not be watched")
+	}
+
+	// A jar in a subdirectory of the recursive root counts, even though the other root contains it
+	if err := os.WriteFile(filepath.Join(libs, "nested", "Plugin.jar"), []byte("built"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-notifier.C:
+	case <-time.After(eventSettleDelay + time.Second):
+		t.Errorf("Expected a signal for a jar below the recursive root")
+	}
+}
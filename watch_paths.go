@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultJarExcludes are jars that sit next to plugin jars in build output but are not
+// plugins themselves, or contain the same plugin unshaded
+var defaultJarExcludes = []string{"*-sources.jar", "*-javadoc.jar", "*-plain.jar"}
+
+// WatchPath is a directory watched for plugin jars. In config files it is either a plain
+// path or an object; on the command line it is a path or the same object as JSON.
+type WatchPath struct {
+	Path      string   `json:"path"`
+	Recursive bool     `json:"recursive,omitempty"` // Also watch jars in subdirectories
+	Include   []string `json:"include,omitempty"`   // Globs for the jars to deploy; all jars when empty
+	Exclude   []string `json:"exclude,omitempty"`   // Globs for jars to skip; defaultJarExcludes when empty
+}
+
+// UnmarshalJSON accepts a plain path as well as the object form
+func (p *WatchPath) UnmarshalJSON(data []byte) error {
+	var plain string
+	if err := json.Unmarshal(data, &plain); err == nil {
+		*p = WatchPath{Path: plain}
+		return nil
+	}
+
+	type watchPathObject WatchPath
+	return json.Unmarshal(data, (*watchPathObject)(p))
+}
+
+// UnmarshalText parses a --watch-paths flag
+func (p *WatchPath) UnmarshalText(text []byte) error {
+	if strings.HasPrefix(strings.TrimSpace(string(text)), "{") {
+		return p.UnmarshalJSON(text)
+	}
+	*p = WatchPath{Path: string(text)}
+	return nil
+}
+
+// validate checks that the path is set and its globs are well formed
+func (p WatchPath) validate() error {
+	if p.Path == "" {
+		return fmt.Errorf("path must be set")
+	}
+	for _, pattern := range append(append([]string(nil), p.Include...), p.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %q for %s", pattern, p.Path)
+		}
+	}
+	return nil
+}
+
+// accepts reports whether the jar at file belongs to this watch path: it is directly inside
+// Path, or below it when Recursive is set, and passes the include and exclude globs
+func (p WatchPath) accepts(file string) bool {
+	root, err := filepath.Abs(p.Path)
+	if err != nil {
+		return false
+	}
+	if absFile, err := filepath.Abs(file); err == nil {
+		file = absFile
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil || rel == "." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if !p.Recursive && strings.Contains(rel, "/") {
+		return false
+	}
+	if !isJarFile(rel) {
+		return false
+	}
+
+	exclude := p.Exclude
+	if len(exclude) == 0 {
+		exclude = defaultJarExcludes
+	}
+	if matchesAnyGlob(exclude, rel) {
+		return false
+	}
+	return len(p.Include) == 0 || matchesAnyGlob(p.Include, rel)
+}
+
+// matchesAnyGlob matches globs without a slash against the file name and the others against
+// the whole relative path, ignoring case like most build outputs
+func matchesAnyGlob(patterns []string, rel string) bool {
+	rel = strings.ToLower(rel)
+	for _, pattern := range patterns {
+		target := rel
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(rel)
+		}
+		if matched, _ := path.Match(strings.ToLower(pattern), target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveWatchPaths returns the directories to watch: the watch option, where auto stands for
+// every Gradle or Maven module's output directory, followed by watchPaths. Configured
+// directories must exist; detected ones may only appear with the first build.
+func resolveWatchPaths() ([]WatchPath, error) {
+	var paths []WatchPath
+	switch config.Watch {
+	case "":
+	case watchAuto:
+		dirs, tool := detectProjectOutputDirs(".")
+		if len(dirs) == 0 {
+			return nil, fmt.Errorf("no Gradle or Maven project found in the current directory, set watch to the directory your plugin jars are built in")
+		}
+		log.Printf("Detected %s project with %d module(s)", tool, len(dirs))
+		for _, dir := range dirs {
+			paths = append(paths, WatchPath{Path: dir})
+		}
+	default:
+		if _, err := os.Stat(config.Watch); err != nil {
+			return nil, fmt.Errorf("cannot watch %s: %v", config.Watch, err)
+		}
+		paths = append(paths, WatchPath{Path: config.Watch})
+	}
+
+	for _, p := range config.WatchPaths {
+		if _, err := os.Stat(p.Path); err != nil {
+			return nil, fmt.Errorf("cannot watch %s: %v", p.Path, err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
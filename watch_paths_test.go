@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWatchPathAccepts(t *testing.T) {
+	root := filepath.Join("build", "libs")
+	tests := []struct {
+		name string
+		path WatchPath
+		file string
+		want bool
+	}{
+		{"plugin jar", WatchPath{Path: root}, "MyPlugin-1.0.jar", true},
+		{"shadow jar", WatchPath{Path: root}, "MyPlugin-1.0-all.jar", true},
+		{"sources jar", WatchPath{Path: root}, "MyPlugin-1.0-sources.jar", false},
+		{"javadoc jar", WatchPath{Path: root}, "MyPlugin-1.0-javadoc.jar", false},
+		{"plain jar", WatchPath{Path: root}, "MyPlugin-1.0-PLAIN.JAR", false},
+		{"not a jar", WatchPath{Path: root}, "MyPlugin-1.0.zip", false},
+		{"outside the root", WatchPath{Path: root}, filepath.Join("..", "Other.jar"), false},
+		{"subdirectory", WatchPath{Path: root}, filepath.Join("old", "MyPlugin.jar"), false},
+		{"subdirectory when recursive", WatchPath{Path: root, Recursive: true}, filepath.Join("old", "MyPlugin.jar"), true},
+		{"include", WatchPath{Path: root, Include: []string{"MyPlugin-*.jar"}}, "Other-1.0.jar", false},
+		{"include path", WatchPath{Path: root, Recursive: true, Include: []string{"api/*.jar"}}, filepath.Join("api", "Api.jar"), true},
+		{"exclude", WatchPath{Path: root, Exclude: []string{"*-dev.jar"}}, "MyPlugin-dev.jar", false},
+		{"exclude replaces defaults", WatchPath{Path: root, Exclude: []string{"*-dev.jar"}}, "MyPlugin-plain.jar", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.path.accepts(filepath.Join(root, test.file)); got != test.want {
+				t.Errorf("accepts(%s) = %v, expected %v", test.file, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWatchPathDecoding(t *testing.T) {
+	var c Config
+	data := `{"watchPaths": ["../lib/build/libs", {"path": "./build", "recursive": true, "exclude": ["*-dev.jar"]}]}`
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatalf("Failed to decode watchPaths: %v", err)
+	}
+	want := []WatchPath{
+		{Path: "../lib/build/libs"},
+		{Path: "./build", Recursive: true, Exclude: []string{"*-dev.jar"}},
+	}
+	if !reflect.DeepEqual(c.WatchPaths, want) {
+		t.Errorf("Expected %+v, got %+v", want, c.WatchPaths)
+	}
+
+	// GOLEM_WATCH_PATHS takes a comma separated list of paths
+	field := reflect.ValueOf(&c).Elem().FieldByName("WatchPaths")
+	if err := parseConfigValue(field, "a, b"); err != nil {
+		t.Fatalf("Failed to parse watch paths: %v", err)
+	}
+	if want := []WatchPath{{Path: "a"}, {Path: "b"}}; !reflect.DeepEqual(c.WatchPaths, want) {
+		t.Errorf("Expected %+v, got %+v", want, c.WatchPaths)
+	}
+
+	var flag WatchPath
+	if err := flag.UnmarshalText([]byte(`{"path": "libs", "include": ["*.jar"]}`)); err != nil {
+		t.Fatalf("Failed to parse flag: %v", err)
+	}
+	if flag.Path != "libs" || len(flag.Include) != 1 {
+		t.Errorf("Unexpected flag value %+v", flag)
+	}
+
+	if err := (WatchPath{Path: "libs", Include: []string{"["}}).validate(); err == nil {
+		t.Errorf("Expected a malformed glob to be rejected")
+	}
+}
+
+// TestPluginWatcherPaths tests watching a plugin and a dependency built in another directory
+func TestPluginWatcherPaths(t *testing.T) {
+	pluginDir, libraryDir := t.TempDir(), t.TempDir()
+	origServerPath := config.ServerPath
+	config.ServerPath = t.TempDir()
+	defer func() { config.ServerPath = origServerPath }()
+
+	writeTestJar(t, filepath.Join(pluginDir, "MyPlugin.jar"), map[string]string{"plugin.yml": "name: MyPlugin\n"})
+	writeTestJar(t, filepath.Join(pluginDir, "MyPlugin-sources.jar"), map[string]string{"plugin.yml": "name: MyPlugin\n"})
+	if err := os.MkdirAll(filepath.Join(libraryDir, "core", "libs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestJar(t, filepath.Join(libraryDir, "core", "libs", "Core.jar"), map[string]string{"plugin.yml": "name: Core\n"})
+	writeTestJar(t, filepath.Join(libraryDir, "core", "libs", "Core-dev.jar"), map[string]string{"plugin.yml": "name: Core\n"})
+
+	watcher := newPluginWatcher([]WatchPath{
+		{Path: pluginDir},
+		{Path: libraryDir, Recursive: true, Exclude: []string{"*-dev.jar"}},
+	}, 0, 0)
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if got := sortedKeys(watcher.pending); !reflect.DeepEqual(got, []string{"Core.jar", "MyPlugin.jar"}) {
+		t.Errorf("Expected Core.jar and MyPlugin.jar to be pending, got %v", got)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// fileSnapshot is the size and modification time of a jar when it was last seen changing
+type fileSnapshot struct {
+	Size    int64
+	ModTime time.Time
+	Since   time.Time // When this size and mtime were first seen
+}
+
+// writeTracker tells whether jars have finished being written, by waiting until their size
+// and mtime have not changed for a quiet period
+type writeTracker struct {
+	quiet    time.Duration
+	seen     map[string]fileSnapshot
+	deferred map[string]string // Last message logged for jars that were not deployed
+}
+
+func newWriteTracker(quiet time.Duration) *writeTracker {
+	return &writeTracker{
+		quiet:    quiet,
+		seen:     make(map[string]fileSnapshot),
+		deferred: make(map[string]string),
+	}
+}
+
+// remaining returns how much longer a jar has to stay unchanged before it counts as fully
+// written, or 0 if it already has
+func (t *writeTracker) remaining(path string, info os.FileInfo, now time.Time) time.Duration {
+	last, ok := t.seen[path]
+	if !ok || last.Size != info.Size() || !last.ModTime.Equal(info.ModTime()) {
+		// A file seen for the first time has been quiet since its mtime, as long as
+		// the clock agrees; anything that just changed starts waiting now
+		since := now
+		if !ok && info.ModTime().Before(now) {
+			since = info.ModTime()
+		}
+		last = fileSnapshot{Size: info.Size(), ModTime: info.ModTime(), Since: since}
+		t.seen[path] = last
+	}
+
+	if waited := now.Sub(last.Since); waited < t.quiet {
+		return t.quiet - waited
+	}
+	return 0
+}
+
+// deferDeploy logs that a jar is not deployed yet, once for each reason
+func (t *writeTracker) deferDeploy(path, reason string) {
+	t.notice(path, fmt.Sprintf("[Golem] Waiting for %s: %s", path, reason))
+}
+
+// reject logs that a jar will not be deployed until it changes, once for each reason
+func (t *writeTracker) reject(path, reason string) {
+	t.notice(path, fmt.Sprintf("[Golem] Skipping %s: %s", path, reason))
+}
+
+func (t *writeTracker) notice(path, message string) {
+	if t.deferred[path] == message {
+		return
+	}
+	t.deferred[path] = message
+	printWithPrompt(message)
+}
+
+// deployed clears the deferral state of a jar once it has been handled
+func (t *writeTracker) deployed(path string) {
+	delete(t.deferred, path)
+}
+
+// forget drops a jar that no longer exists
+func (t *writeTracker) forget(path string) {
+	delete(t.seen, path)
+	delete(t.deferred, path)
+}
+
+// validateJar checks that a jar is a complete zip archive, which a partly written one is not
+func validateJar(path string) error {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if len(reader.File) == 0 {
+		return fmt.Errorf("archive is empty")
+	}
+	return nil
+}
+
+// settled returns the file info of a jar that has stopped changing, or nil when it has not.
+// Then the deferral is logged and the time after which it is worth checking again is returned.
+func (t *writeTracker) settled(path string) (os.FileInfo, time.Duration) {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf("Warning: Failed to stat %s: %v", path, err)
+		return nil, 0
+	}
+
+	if wait := t.remaining(path, info, time.Now()); wait > 0 {
+		t.deferDeploy(path, "still being written")
+		return nil, wait
+	}
+	return info, 0
+}
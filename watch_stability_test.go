@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestJar writes a minimal jar containing the given files
+func writeTestJar(t testing.TB, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteTrackerRemaining(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Plugin.jar")
+	if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := newWriteTracker(time.Second)
+	modTime := info.ModTime()
+
+	// A jar first seen long after its last write is settled straight away
+	if wait := tracker.remaining(path, info, modTime.Add(2*time.Second)); wait != 0 {
+		t.Errorf("Old jar should be settled, got wait %v", wait)
+	}
+
+	// A jar seen right after a write has to wait for the quiet period
+	tracker = newWriteTracker(time.Second)
+	if wait := tracker.remaining(path, info, modTime.Add(200*time.Millisecond)); wait != 800*time.Millisecond {
+		t.Errorf("Expected 800ms wait, got %v", wait)
+	}
+	if wait := tracker.remaining(path, info, modTime.Add(time.Second)); wait != 0 {
+		t.Errorf("Unchanged jar should be settled after the quiet period, got wait %v", wait)
+	}
+
+	// Growing again restarts the quiet period from when the change was seen
+	if err := os.WriteFile(path, []byte("partial, more data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	grown, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := modTime.Add(5 * time.Second)
+	if wait := tracker.remaining(path, grown, now); wait != time.Second {
+		t.Errorf("Changed jar should wait the full quiet period, got %v", wait)
+	}
+}
+
+func TestValidateJar(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "Valid.jar")
+	writeTestJar(t, valid, map[string]string{"plugin.yml": "name: Valid\n"})
+	if err := validateJar(valid); err != nil {
+		t.Errorf("Expected valid jar, got %v", err)
+	}
+
+	// Cut the central directory off, as if the build was still writing it
+	data, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(dir, "Truncated.jar")
+	if err := os.WriteFile(truncated, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateJar(truncated); err == nil {
+		t.Errorf("Expected truncated jar to be rejected")
+	}
+
+	empty := filepath.Join(dir, "Empty.jar")
+	writeTestJar(t, empty, nil)
+	if err := validateJar(empty); err == nil {
+		t.Errorf("Expected empty jar to be rejected")
+	}
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
-	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"syscall"
@@ -19,11 +21,67 @@ import (
 type Plugin struct {
 	Path      string    // Full path to the plugin JAR
 	Name      string    // Just the filename
-	Hash      string    // MD5 hash of the file
+	Hash      string    // SHA-256 hash of the file
 	LastCheck time.Time // Last time this plugin was checked
+
+	Descriptor *PluginDescriptor // Metadata from the jar's plugin.yml or equivalent
 }
 
-// calculateFileHash returns the MD5 hash of a file
+// changeKind says what happened to a plugin jar in the watch directory
+type changeKind int
+
+const (
+	pluginAdded changeKind = iota
+	pluginModified
+	pluginRemoved
+)
+
+// pluginChange is a detected change that has not been deployed yet
+type pluginChange struct {
+	Kind       changeKind
+	Name       string
+	Path       string
+	Hash       string
+	Descriptor *PluginDescriptor
+}
+
+// pluginWatcher tracks the jars in the watch directories and collects changes into batches,
+// so that several jars written by one build are deployed with a single restart
+type pluginWatcher struct {
+	paths      []WatchPath
+	plugins    map[string]*Plugin       // Deployed plugins, by file name
+	hashes     map[string]hashedFile    // Hashes of the watched jars, by path
+	pending    map[string]*pluginChange // Changes waiting to be deployed, by file name
+	lastChange time.Time                // When the pending batch last grew
+	debounce   time.Duration
+	tracker    *writeTracker
+}
+
+func newPluginWatcher(paths []WatchPath, debounce, quietPeriod time.Duration) *pluginWatcher {
+	return &pluginWatcher{
+		paths:    paths,
+		plugins:  make(map[string]*Plugin),
+		hashes:   make(map[string]hashedFile),
+		pending:  make(map[string]*pluginChange),
+		debounce: debounce,
+		tracker:  newWriteTracker(quietPeriod),
+	}
+}
+
+// hashedFile is a jar's hash along with the size and mtime it had when it was hashed.
+// While those stay the same the jar is assumed unchanged and is not read again.
+type hashedFile struct {
+	Stamp fileStamp
+	Hash  string
+}
+
+// hashResult is the outcome of hashing one file
+type hashResult struct {
+	Hash string
+	Err  error
+}
+
+// calculateFileHash returns the SHA-256 hash of a file
 func calculateFileHash(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -31,7 +89,7 @@ func calculateFileHash(filePath string) (string, error) {
 	}
 	defer file.Close()
 
-	hash := md5.New()
+	hash := sha256.New()
 	if _, err := io.Copy(hash, file); err != nil {
 		return "", fmt.Errorf("failed to calculate hash: %v", err)
 	}
@@ -39,21 +97,56 @@ func calculateFileHash(filePath string) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-// watchPluginDevelopment starts watching a directory for plugin changes
-func watchPluginDevelopment(watchDir string) {
-	log.Printf("Starting development watch mode for directory: %s", watchDir)
-	
-	// Create plugins map with file hashes
-	plugins := make(map[string]*Plugin)
-	pluginsMutex := &sync.Mutex{}
+// hashFiles hashes files in parallel, for watch directories where a build touches many jars
+func hashFiles(paths []string) map[string]hashResult {
+	results := make(map[string]hashResult, len(paths))
+	if len(paths) == 0 {
+		return results
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for i := 0; i < min(runtime.GOMAXPROCS(0), len(paths)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range queue {
+				hash, err := calculateFileHash(path)
+				mu.Lock()
+				results[path] = hashResult{Hash: hash, Err: err}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range paths {
+		queue <- path
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// watchPluginDevelopment starts watching directories for plugin changes
+func watchPluginDevelopment(watchPaths []WatchPath) {
+	// Durations were validated when the config was loaded
+	debounce, _ := time.ParseDuration(config.WatchDebounce)
+	quietPeriod, _ := time.ParseDuration(config.WatchQuietPeriod)
+	watcher := newPluginWatcher(watchPaths, debounce, quietPeriod)
 
 	// Set up clean exit
 	exitCh := make(chan os.Signal, 1)
 	signal.Notify(exitCh, os.Interrupt, syscall.SIGTERM)
-	
+
 	// Print help message
 	fmt.Println("==== Plugin Development Mode ====")
-	fmt.Printf("Watching directory: %s\n", watchDir)
+	for _, p := range watchPaths {
+		if p.Recursive {
+			fmt.Printf("Watching directory: %s (recursive)\n", p.Path)
+		} else {
+			fmt.Printf("Watching directory: %s\n", p.Path)
+		}
+	}
 	fmt.Printf("Server plugins directory: %s/plugins\n", config.ServerPath)
 	fmt.Println("Plugin changes will automatically trigger server restarts")
 	fmt.Println("====================================")
@@ -64,134 +157,76 @@ func watchPluginDevelopment(watchDir string) {
 		return
 	}
 
-	// Scan for initial plugins
-	files, err := os.ReadDir(watchDir)
+	// Copy the plugins that are already built to the server directory
+	retry, err := watcher.scan()
 	if err != nil {
 		log.Printf("Failed to read watch directory: %v", err)
 		return
 	}
-
-	// Create initial plugins map with hashes
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".jar") {
-			pluginPath := filepath.Join(watchDir, file.Name())
-			hash, err := calculateFileHash(pluginPath)
-			if err != nil {
-				log.Printf("Warning: Failed to calculate hash for %s: %v", pluginPath, err)
-				continue
-			}
-			
-			plugins[file.Name()] = &Plugin{
-				Path:      pluginPath,
-				Name:      file.Name(),
-				Hash:      hash,
-				LastCheck: time.Now(),
-			}
-			log.Printf("Found plugin: %s (hash: %s)", file.Name(), hash[:8])
-		}
-	}
-
-	// Copy plugins to server directory
-	for _, plugin := range plugins {
-		if err := updatePlugin(plugin.Path); err != nil {
-			log.Printf("Failed to copy plugin %s: %v", plugin.Name, err)
-		}
-	}
+	watcher.deploy()
 
 	// Start server if needed
 	if args.AutoStart {
-		if err := startServer(); err != nil {
+		if err := server.Start(); err != nil {
 			log.Printf("Failed to start server: %v", err)
 			return
 		}
+		startConsole()
 	}
 
 	// Start watching for changes in background
-	ticker := time.NewTicker(2 * time.Second)
+	notifier := newChangeNotifier(watchPaths, watcher.accepts)
+	if retry > 0 {
+		notifier.recheckAfter(retry)
+	}
 	finished := make(chan struct{})
 
+	// Build the plugin whenever its sources change, if a build command is configured
+	var builder *buildRunner
+	var buildResults <-chan bool
+	if config.BuildCommand != "" {
+		builder = newBuildRunner()
+		buildResults = builder.Finished
+		builder.Start(finished)
+	}
+
 	// Watch for changes in a separate goroutine
 	go func() {
 		defer close(finished)
-		defer ticker.Stop()
+		defer notifier.Close()
 
 		for {
 			select {
-			case <-ticker.C:
-				// Scan directory for changed plugins
-				files, err := os.ReadDir(watchDir)
+			case <-notifier.C:
+				retry, err := watcher.scan()
 				if err != nil {
 					log.Printf("Failed to read watch directory: %v", err)
 					continue
 				}
 
-				restartNeeded := false
-				pluginsMutex.Lock()
-
-				// Check each JAR file
-				for _, file := range files {
-					if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".jar") {
-						pluginPath := filepath.Join(watchDir, file.Name())
-						hash, err := calculateFileHash(pluginPath)
-						if err != nil {
-							log.Printf("Warning: Failed to calculate hash for %s: %v", pluginPath, err)
-							continue
-						}
-
-						// Check if plugin exists or has changed
-						plugin, exists := plugins[file.Name()]
-						if !exists {
-							// New plugin found
-							log.Printf("DEBUG: New plugin detected: %s (hash: %s)", file.Name(), hash[:8])
-							plugin = &Plugin{
-								Path:      pluginPath,
-								Name:      file.Name(),
-								Hash:      hash,
-								LastCheck: time.Now(),
-							}
-							plugins[file.Name()] = plugin
-							printWithPrompt(fmt.Sprintf("[Golem] New plugin detected: %s", file.Name()))
-							
-							log.Printf("DEBUG: Copying plugin %s to server plugins directory", file.Name())
-							if err := updatePlugin(pluginPath); err != nil {
-								log.Printf("Failed to copy plugin %s: %v", file.Name(), err)
-								continue
-							}
-							
-							restartNeeded = true
-						} else if plugin.Hash != hash {
-							// Plugin has changed
-							log.Printf("DEBUG: Plugin changed: %s (old hash: %s, new hash: %s)", file.Name(), plugin.Hash[:8], hash[:8])
-							printWithPrompt(fmt.Sprintf("[Golem] Plugin changed: %s (hash: %s → %s)", file.Name(), plugin.Hash[:8], hash[:8]))
-							plugin.Hash = hash
-							plugin.LastCheck = time.Now()
-							
-							log.Printf("DEBUG: Copying updated plugin %s to server plugins directory", file.Name())
-							if err := updatePlugin(pluginPath); err != nil {
-								log.Printf("Failed to copy plugin %s: %v", file.Name(), err)
-								continue
-							}
-							
-							restartNeeded = true
-						} else {
-							// Plugin hasn't changed, update last check time
-							plugin.LastCheck = time.Now()
-						}
-					}
+				// Hold the batch back while jars are still being written or more may follow
+				if wait := earliestRetry(retry, watcher.debounceRemaining(time.Now())); wait > 0 {
+					notifier.recheckAfter(wait)
+					continue
 				}
-				pluginsMutex.Unlock()
-
-				// Restart server if needed
-				if restartNeeded {
-					printWithPrompt("[Golem] Restarting server to apply plugin changes...")
-					if err := restartServer(); err != nil {
-						log.Printf("Failed to restart server: %v", err)
-					}
+
+				// Jars from a build that is still running or has failed are not deployed;
+				// the batch is picked up again once a build succeeds
+				if builder != nil && !builder.ready() {
+					continue
 				}
-				
+
+				// Bring the changes into the server, by restart or reload
+				applyPluginChanges(watcher.deploy())
+
+			case ok := <-buildResults:
+				if ok {
+					notifier.signal()
+				}
+
 			case <-exitCh:
 				printWithPrompt("\n[Golem] Stopping plugin watcher and server...")
-				stopServer()
+				server.Stop()
 				return
 			}
 		}
@@ -202,15 +237,295 @@ func watchPluginDevelopment(watchDir string) {
 	fmt.Println("Golem plugin development mode exited")
 }
 
-func updatePlugin(pluginPath string) error {
-	// Copy new plugin to server's plugin directory
-	destPath := filepath.Join(config.ServerPath, "plugins", filepath.Base(pluginPath))
-	log.Printf("Copying plugin %s to %s", pluginPath, destPath)
+// scan compares the watch directories with the deployed plugins and records the differences
+// as pending changes. It returns how long to wait before jars that are still being
+// written are worth checking again, or 0 if none are.
+func (w *pluginWatcher) scan() (time.Duration, error) {
+	jars, err := w.listJars()
+	if err != nil {
+		return 0, err
+	}
+
+	var retry time.Duration
+	var ready, toHash []string
+	present := make(map[string]bool)
+	listed := make(map[string]bool)
+	stamps := make(map[string]fileStamp)
+
+	// Only jars whose size or mtime changed since they were last hashed are read
+	for _, pluginPath := range jars {
+		present[filepath.Base(pluginPath)] = true
+		listed[pluginPath] = true
+
+		// Leave jars that are still being written for a later scan
+		info, wait := w.tracker.settled(pluginPath)
+		if info == nil {
+			retry = earliestRetry(retry, wait)
+			continue
+		}
+
+		ready = append(ready, pluginPath)
+		stamps[pluginPath] = stampOf(info)
+		if cached, ok := w.hashes[pluginPath]; !ok || !cached.Stamp.equal(stamps[pluginPath]) {
+			toHash = append(toHash, pluginPath)
+		}
+	}
+	for path, result := range hashFiles(toHash) {
+		if result.Err != nil {
+			log.Printf("Warning: Failed to calculate hash for %s: %v", path, result.Err)
+			delete(w.hashes, path)
+			continue
+		}
+		w.hashes[path] = hashedFile{Stamp: stamps[path], Hash: result.Hash}
+	}
+	for path := range w.hashes {
+		if !listed[path] {
+			delete(w.hashes, path)
+		}
+	}
+
+	// Check each JAR file
+	for _, pluginPath := range ready {
+		name := filepath.Base(pluginPath)
+		hashed, ok := w.hashes[pluginPath]
+		if !ok {
+			continue
+		}
+		hash := hashed.Hash
+
+		plugin, exists := w.plugins[name]
+		if exists && plugin.Hash == hash {
+			// Plugin hasn't changed (or was changed back), update last check time
+			plugin.LastCheck = time.Now()
+			delete(w.pending, name)
+			continue
+		}
+		if change := w.pending[name]; change != nil && change.Hash == hash {
+			continue
+		}
+
+		// A truncated jar would make the server fail with "zip END header not found"
+		if err := validateJar(pluginPath); err != nil {
+			w.tracker.deferDeploy(pluginPath, fmt.Sprintf("not a valid jar yet (%v)", err))
+			continue
+		}
+
+		// Jars without a descriptor are libraries or build leftovers, not plugins
+		descriptor, err := readPluginDescriptor(pluginPath)
+		if err != nil {
+			w.tracker.reject(pluginPath, err.Error())
+			continue
+		}
+		w.tracker.deployed(pluginPath)
+
+		kind := pluginAdded
+		if exists {
+			kind = pluginModified
+			version := descriptor.String()
+			if plugin.Descriptor.Version != descriptor.Version {
+				version = fmt.Sprintf("%s → %s", plugin.Descriptor, descriptor.Version)
+			}
+			log.Printf("Plugin changed: %s (%s, hash: %s → %s)", version, name, plugin.Hash[:8], hash[:8])
+		} else {
+			log.Printf("New plugin detected: %s (%s, hash: %s)", descriptor, name, hash[:8])
+		}
+		w.addChange(&pluginChange{Kind: kind, Name: name, Path: pluginPath, Hash: hash, Descriptor: descriptor})
+	}
+
+	// Plugins whose jar has gone from the watch directory
+	for name, plugin := range w.plugins {
+		if present[name] {
+			continue
+		}
+		w.tracker.forget(plugin.Path)
+		if change := w.pending[name]; change == nil || change.Kind != pluginRemoved {
+			log.Printf("Plugin removed: %s (%s)", plugin.Descriptor, name)
+			w.addChange(&pluginChange{Kind: pluginRemoved, Name: name, Path: plugin.Path, Descriptor: plugin.Descriptor})
+		}
+	}
+
+	// A jar that appeared and disappeared again before being deployed
+	for name, change := range w.pending {
+		if change.Kind != pluginRemoved && !present[name] {
+			delete(w.pending, name)
+		}
+	}
+
+	return retry, nil
+}
+
+// listJars returns the plugin jars in the watch paths. Directories that don't exist yet,
+// like build/libs before the first build, are skipped. Plugins are deployed by file name,
+// so when two directories hold a jar of the same name the first one is used.
+func (w *pluginWatcher) listJars() ([]string, error) {
+	var jars []string
+	found := make(map[string]string)
+	for _, p := range w.paths {
+		err := filepath.WalkDir(p.Path, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == p.Path {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if entry.IsDir() {
+				if path != p.Path && !p.Recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !p.accepts(path) {
+				return nil
+			}
+			if first, ok := found[entry.Name()]; ok {
+				if first != path {
+					w.tracker.notice(path, fmt.Sprintf("[Golem] Ignoring %s, %s has the same file name", path, first))
+				}
+				return nil
+			}
+			found[entry.Name()] = path
+			jars = append(jars, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jars, nil
+}
+
+// accepts reports whether a file is a jar in one of the watch paths
+func (w *pluginWatcher) accepts(path string) bool {
+	for _, p := range w.paths {
+		if p.accepts(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// addChange adds a change to the pending batch and restarts the debounce window
+func (w *pluginWatcher) addChange(change *pluginChange) {
+	w.pending[change.Name] = change
+	w.lastChange = time.Now()
+}
+
+// debounceRemaining returns how long to wait for further changes before the pending batch
+// is deployed, or 0 if it can be deployed now
+func (w *pluginWatcher) debounceRemaining(now time.Time) time.Duration {
+	if len(w.pending) == 0 {
+		return 0
+	}
+	if waited := now.Sub(w.lastChange); waited < w.debounce {
+		return w.debounce - waited
+	}
+	return 0
+}
+
+// deploy copies the pending batch to the server in dependency order, prints a one-line
+// summary of it along with any dependency problems, and returns the changes that were made
+// to the server's plugins directory
+func (w *pluginWatcher) deploy() []*pluginChange {
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	var summary []string
+	var applied []*pluginChange
+	for _, change := range orderPluginChanges(w.pending) {
+		name := change.Name
+		delete(w.pending, name)
+
+		switch change.Kind {
+		case pluginAdded, pluginModified:
+			if err := removeStalePluginJars(change.Descriptor, name); err != nil {
+				log.Printf("Failed to remove older jars of %s: %v", change.Descriptor.Name, err)
+				continue
+			}
+			if err := updatePlugin(change.Path, change.Hash); err != nil {
+				log.Printf("Failed to copy plugin %s: %v", name, err)
+				continue
+			}
+			w.plugins[name] = &Plugin{
+				Path:      change.Path,
+				Name:      name,
+				Hash:      change.Hash,
+				LastCheck: time.Now(),
+
+				Descriptor: change.Descriptor,
+			}
+		case pluginRemoved:
+			deployed := filepath.Join(serverPluginsDir(), name)
+			if _, err := os.Lstat(deployed); config.WatchKeepDeleted || os.IsNotExist(err) {
+				// The copy in the server's plugins directory is left in place, or already gone
+				delete(w.plugins, name)
+				summary = append(summary, formatPluginChange(change))
+				continue
+			}
+			if err := disablePluginJar(deployed); err != nil {
+				// Still tracked and pending, so the removal is tried again with the next batch
+				log.Printf("Failed to remove plugin %s: %v", name, err)
+				w.pending[name] = change
+				continue
+			}
+			delete(w.plugins, name)
+		}
+		summary = append(summary, formatPluginChange(change))
+		applied = append(applied, change)
+	}
+
+	if len(summary) > 0 {
+		printWithPrompt(fmt.Sprintf("[Golem] %d plugin change(s): %s", len(summary), strings.Join(summary, ", ")))
+	}
+	w.checkPluginDependencies(applied)
+	return applied
+}
+
+// formatPluginChange renders a change for the batch summary, e.g. "+MyPlugin 1.2.0"
+func formatPluginChange(change *pluginChange) string {
+	switch change.Kind {
+	case pluginAdded:
+		return "+" + change.Descriptor.String()
+	case pluginModified:
+		return "~" + change.Descriptor.String()
+	default:
+		return "-" + change.Descriptor.String()
+	}
+}
+
+// earliestRetry returns the sooner of two retry delays, where 0 means no retry
+func earliestRetry(current, wait time.Duration) time.Duration {
+	if wait > 0 && (current == 0 || wait < current) {
+		return wait
+	}
+	return current
+}
+
+// updatePlugin puts a plugin jar into the server's plugins directory using deployMode,
+// checking that the deployed jar has the hash it was watched with
+func updatePlugin(pluginPath, hash string) error {
+	destPath := filepath.Join(serverPluginsDir(), filepath.Base(pluginPath))
 
 	// Create plugins directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Join(config.ServerPath, "plugins"), 0755); err != nil {
+	if err := os.MkdirAll(serverPluginsDir(), 0755); err != nil {
 		return fmt.Errorf("failed to create plugins directory: %v", err)
 	}
 
-	return copyFile(pluginPath, destPath)
+	switch config.DeployMode {
+	case deploySymlink:
+		log.Printf("Linking plugin %s to %s", pluginPath, destPath)
+		return linkPluginJar(pluginPath, destPath, hash, true)
+	case deployHardlink:
+		log.Printf("Hard linking plugin %s to %s", pluginPath, destPath)
+		err := linkPluginJar(pluginPath, destPath, hash, false)
+		if isCrossDeviceError(err) {
+			// Hard links can't cross filesystems, e.g. from a project into a mounted server
+			log.Printf("Cannot hard link %s across filesystems, copying it instead", filepath.Base(pluginPath))
+			return copyPluginJar(pluginPath, destPath, hash)
+		}
+		return err
+	default:
+		log.Printf("Copying plugin %s to %s", pluginPath, destPath)
+		return copyPluginJar(pluginPath, destPath, hash)
+	}
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"testing"
@@ -228,7 +229,11 @@ func TestUpdatePlugin(t *testing.T) {
 	// Test plugin copying
 	t.Run("Copies plugin to server directory", func(t *testing.T) {
 		// Run the updatePlugin function
-		err := updatePlugin(plugin1Path)
+		hash, err := calculateFileHash(plugin1Path)
+		if err != nil {
+			t.Fatalf("Failed to hash plugin: %v", err)
+		}
+		err = updatePlugin(plugin1Path, hash)
 		if err != nil {
 			t.Fatalf("Failed to update plugin: %v", err)
 		}
@@ -259,7 +264,11 @@ func TestUpdatePlugin(t *testing.T) {
 		}
 
 		// Run the updatePlugin function again
-		err := updatePlugin(plugin1Path)
+		hash, err := calculateFileHash(plugin1Path)
+		if err != nil {
+			t.Fatalf("Failed to hash plugin: %v", err)
+		}
+		err = updatePlugin(plugin1Path, hash)
 		if err != nil {
 			t.Fatalf("Failed to update plugin: %v", err)
 		}
@@ -292,3 +301,245 @@ func createTestJar(path, content string) error {
 	
 	return nil
 }
+
+// TestPluginWatcherBatch tests that changes are collected into one batch and deployed together
+func TestPluginWatcherBatch(t *testing.T) {
+	watchDir := t.TempDir()
+	origServerPath := config.ServerPath
+	config.ServerPath = t.TempDir()
+	defer func() { config.ServerPath = origServerPath }()
+
+	writeTestJar(t, filepath.Join(watchDir, "Core.jar"), map[string]string{"plugin.yml": "name: Core\n"})
+	watcher := newPluginWatcher([]WatchPath{{Path: watchDir}}, time.Second, 0)
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	watcher.deploy()
+
+	// A multi-module build: one jar changes, one appears and one goes away
+	writeTestJar(t, filepath.Join(watchDir, "Core.jar"), map[string]string{"plugin.yml": "name: Core\nversion: 2\n"})
+	writeTestJar(t, filepath.Join(watchDir, "Addon.jar"), map[string]string{"plugin.yml": "name: Addon\n"})
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(watcher.pending) != 2 {
+		t.Fatalf("Expected 2 pending changes, got %d", len(watcher.pending))
+	}
+	if wait := watcher.debounceRemaining(time.Now()); wait <= 0 {
+		t.Errorf("Batch should wait for the debounce window")
+	}
+
+	// Scanning again without further changes keeps the same batch
+	lastChange := watcher.lastChange
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if !watcher.lastChange.Equal(lastChange) {
+		t.Errorf("Rescanning unchanged jars should not extend the debounce window")
+	}
+	if wait := watcher.debounceRemaining(lastChange.Add(time.Second)); wait != 0 {
+		t.Errorf("Batch should be ready after the debounce window, got wait %v", wait)
+	}
+
+	if len(watcher.deploy()) != 2 {
+		t.Errorf("Both changed jars should have been deployed")
+	}
+	if len(watcher.pending) != 0 {
+		t.Errorf("Pending changes should be cleared after deploying")
+	}
+	if _, err := os.Stat(filepath.Join(config.ServerPath, "plugins", "Addon.jar")); err != nil {
+		t.Errorf("Addon.jar was not deployed: %v", err)
+	}
+
+	// A removal is part of the next batch
+	if err := os.Remove(filepath.Join(watchDir, "Addon.jar")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if change := watcher.pending["Addon.jar"]; change == nil || change.Kind != pluginRemoved {
+		t.Errorf("Expected Addon.jar to be pending removal, got %+v", change)
+	}
+
+	// Deploying the removal takes the plugin out of the server
+	if len(watcher.deploy()) != 1 {
+		t.Errorf("Removing a deployed plugin should change the server")
+	}
+	if _, err := os.Stat(filepath.Join(config.ServerPath, "plugins", "Addon.jar")); !os.IsNotExist(err) {
+		t.Errorf("Addon.jar should have been removed from the plugins directory")
+	}
+	if _, err := os.Stat(filepath.Join(config.ServerPath, "plugins", disabledPluginsDir, "Addon.jar")); err != nil {
+		t.Errorf("Addon.jar should have been moved to %s: %v", disabledPluginsDir, err)
+	}
+}
+
+// TestPluginWatcherRetriesFailedRemoval tests that a plugin that could not be removed from the
+// server stays tracked and pending
+func TestPluginWatcherRetriesFailedRemoval(t *testing.T) {
+	watchDir := t.TempDir()
+	origConfig := config
+	config.ServerPath = t.TempDir()
+	defer func() { config = origConfig }()
+
+	jarPath := filepath.Join(watchDir, "Core.jar")
+	writeTestJar(t, jarPath, map[string]string{"plugin.yml": "name: Core\n"})
+	watcher := newPluginWatcher([]WatchPath{{Path: watchDir}}, 0, 0)
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	watcher.deploy()
+
+	// A file in the way of the disabled plugins directory makes the removal fail
+	blocker := filepath.Join(config.ServerPath, "plugins", disabledPluginsDir)
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(jarPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(watcher.deploy()) != 0 {
+		t.Errorf("A failed removal should not be reported as applied")
+	}
+	if watcher.plugins["Core.jar"] == nil || watcher.pending["Core.jar"] == nil {
+		t.Fatalf("A failed removal should stay tracked and pending")
+	}
+
+	// Once the problem is gone the removal goes through
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if len(watcher.deploy()) != 1 {
+		t.Errorf("The retried removal should change the server")
+	}
+	if _, err := os.Stat(filepath.Join(config.ServerPath, "plugins", "Core.jar")); !os.IsNotExist(err) {
+		t.Errorf("Core.jar should have been removed from the plugins directory")
+	}
+	if len(watcher.plugins) != 0 || len(watcher.pending) != 0 {
+		t.Errorf("Removed plugin should no longer be tracked")
+	}
+}
+
+// TestPluginWatcherKeepDeleted tests that watchKeepDeleted leaves deployed plugins in place
+func TestPluginWatcherKeepDeleted(t *testing.T) {
+	watchDir := t.TempDir()
+	origConfig := config
+	config.ServerPath = t.TempDir()
+	config.WatchKeepDeleted = true
+	defer func() { config = origConfig }()
+
+	jarPath := filepath.Join(watchDir, "Core.jar")
+	writeTestJar(t, jarPath, map[string]string{"plugin.yml": "name: Core\n"})
+	watcher := newPluginWatcher([]WatchPath{{Path: watchDir}}, 0, 0)
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	watcher.deploy()
+
+	if err := os.Remove(jarPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(watcher.deploy()) != 0 {
+		t.Errorf("A kept plugin should not change the server")
+	}
+	if _, err := os.Stat(filepath.Join(config.ServerPath, "plugins", "Core.jar")); err != nil {
+		t.Errorf("Core.jar should have been kept: %v", err)
+	}
+	if len(watcher.plugins) != 0 {
+		t.Errorf("Removed plugin should no longer be tracked")
+	}
+}
+
+// TestPluginWatcherSkipsUntouchedJars tests that a jar is only hashed again when its size or mtime changes
+func TestPluginWatcherSkipsUntouchedJars(t *testing.T) {
+	watchDir := t.TempDir()
+	jarPath := filepath.Join(watchDir, "Core.jar")
+	writeTestJar(t, jarPath, map[string]string{"plugin.yml": "name: Core\nversion: 1\n"})
+	watcher := newPluginWatcher([]WatchPath{{Path: watchDir}}, 0, 0)
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	info, err := os.Stat(jarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := watcher.hashes[jarPath].Hash
+
+	// Same size and mtime: the cached hash is trusted, so the new content goes unnoticed
+	writeTestJar(t, jarPath, map[string]string{"plugin.yml": "name: Core\nversion: 2\n"})
+	if err := os.Chtimes(jarPath, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if watcher.hashes[jarPath].Hash != hash {
+		t.Errorf("A jar with the same size and mtime should not have been hashed again")
+	}
+
+	// A new mtime makes the jar count as touched
+	touched := info.ModTime().Add(-time.Minute)
+	if err := os.Chtimes(jarPath, touched, touched); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if watcher.hashes[jarPath].Hash == hash {
+		t.Errorf("A jar with a new mtime should have been hashed again")
+	}
+
+	if err := os.Remove(jarPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.scan(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(watcher.hashes) != 0 {
+		t.Errorf("Hashes of removed jars should be dropped")
+	}
+}
+
+// BenchmarkPluginWatcherScan measures scanning a directory of 200 jars of 1MB each
+func BenchmarkPluginWatcherScan(b *testing.B) {
+	watchDir := b.TempDir()
+	// Random content, so that the jars don't compress to nothing
+	padding := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(padding)
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("Plugin%d", i)
+		writeTestJar(b, filepath.Join(watchDir, name+".jar"), map[string]string{
+			"plugin.yml": "name: " + name + "\n",
+			"data.bin":   string(padding),
+		})
+	}
+
+	b.Run("unchanged", func(b *testing.B) {
+		watcher := newPluginWatcher([]WatchPath{{Path: watchDir}}, 0, 0)
+		if _, err := watcher.scan(); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := watcher.scan(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("all touched", func(b *testing.B) {
+		watcher := newPluginWatcher([]WatchPath{{Path: watchDir}}, 0, 0)
+		for i := 0; i < b.N; i++ {
+			watcher.hashes = make(map[string]hashedFile)
+			if _, err := watcher.scan(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
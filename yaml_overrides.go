@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigOverrides maps a YAML file inside the server directory (e.g. "bukkit.yml" or
+// "config/paper-global.yml") to the values to set in it, keyed by dotted path
+type ConfigOverrides map[string]map[string]interface{}
+
+// applyConfigOverrides sets the configured values in the server's YAML config files,
+// keeping comments and the order of everything else, and prints what changed
+func applyConfigOverrides() error {
+	for _, file := range sortedKeys(config.ConfigOverrides) {
+		path, err := safeJoin(config.ServerPath, file)
+		if err != nil {
+			return fmt.Errorf("invalid configOverrides file: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %v", file, err)
+		}
+
+		updated, changes, err := mergeYAMLOverrides(data, config.ConfigOverrides[file])
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %v", file, err)
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %v", file, err)
+		}
+		if err := os.WriteFile(path, updated, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", file, err)
+		}
+
+		log.Printf("Updated %s:", file)
+		for _, change := range changes {
+			if change.Inserted {
+				log.Printf("  + %s: %s", change.Key, change.New)
+			} else {
+				log.Printf("  ~ %s: %s → %s", change.Key, change.Old, change.New)
+			}
+		}
+	}
+	return nil
+}
+
+// mergeYAMLOverrides sets the values at their dotted paths in a YAML document and returns
+// the new document along with the values that actually changed
+func mergeYAMLOverrides(data []byte, values map[string]interface{}) ([]byte, []propertyChange, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var changes []propertyChange
+	for _, key := range sortedKeys(values) {
+		keys := splitDottedPath(key)
+		value := normalizeYAMLValue(values[key])
+		desired := formatYAMLValue(value)
+
+		change := propertyChange{Key: key, New: desired, Inserted: true}
+		if existing := findNode(doc.Content[0], keys); existing != nil {
+			var current interface{}
+			if err := existing.Decode(&current); err == nil && sameYAMLValue(current, value) {
+				continue
+			}
+			change.Old = formatYAMLNode(existing)
+			change.Inserted = false
+		}
+
+		if err := setNodeValue(doc.Content[0], value, keys); err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	if len(changes) == 0 {
+		return data, nil, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), changes, nil
+}
+
+// splitDottedPath splits "settings.connection-throttle" into its keys; "\." keeps a literal dot
+func splitDottedPath(path string) []string {
+	var keys []string
+	var current strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			current.WriteByte('.')
+			i++
+		case path[i] == '.':
+			keys = append(keys, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	return append(keys, current.String())
+}
+
+// findNode returns the value node at the key path below a mapping, or nil if it is missing
+func findNode(node *yaml.Node, keys []string) *yaml.Node {
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// normalizeYAMLValue turns whole numbers decoded from the config as float64 back into
+// integers, so they are written as 4000 rather than 4000.0 or 4e+03
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[key] = normalizeYAMLValue(item)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeYAMLValue(item)
+		}
+		return normalized
+	}
+	return value
+}
+
+// sameYAMLValue compares values through JSON so that 1 from YAML equals 1.0 from the config
+func sameYAMLValue(a, b interface{}) bool {
+	left, errLeft := json.Marshal(a)
+	right, errRight := json.Marshal(b)
+	return errLeft == nil && errRight == nil && bytes.Equal(left, right)
+}
+
+// formatYAMLValue renders a value compactly for the change log
+func formatYAMLValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func formatYAMLNode(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return "?"
+	}
+	return formatYAMLValue(value)
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+// TestMergeYAMLOverrides tests that overrides keep comments and key order of the rest of the file
+func TestMergeYAMLOverrides(t *testing.T) {
+	content := `# This is the main configuration file for Bukkit.
+settings:
+  allow-end: true
+  connection-throttle: 4000 # milliseconds
+  shutdown-message: Server closed
+chunk-gc:
+  period-in-ticks: 600
+`
+	values := map[string]interface{}{
+		"settings.connection-throttle": float64(-1),
+		"settings.allow-end":           true,
+		"chunk-gc.period-in-ticks":     float64(0),
+		"ticks-per.autosave":           float64(6000),
+	}
+
+	merged, changes, err := mergeYAMLOverrides([]byte(content), values)
+	if err != nil {
+		t.Fatalf("Failed to merge overrides: %v", err)
+	}
+
+	expected := `# This is the main configuration file for Bukkit.
+settings:
+  allow-end: true
+  connection-throttle: -1 # milliseconds
+  shutdown-message: Server closed
+chunk-gc:
+  period-in-ticks: 0
+ticks-per:
+  autosave: 6000
+`
+	if string(merged) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, merged)
+	}
+
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d: %+v", len(changes), changes)
+	}
+	if changes[0].Key != "chunk-gc.period-in-ticks" || changes[0].Old != "600" || changes[0].New != "0" {
+		t.Errorf("Unexpected change: %+v", changes[0])
+	}
+	if !changes[2].Inserted || changes[2].Key != "ticks-per.autosave" {
+		t.Errorf("Expected ticks-per.autosave to be inserted, got %+v", changes[2])
+	}
+
+	t.Run("Unchanged file is not rewritten", func(t *testing.T) {
+		again, changes, err := mergeYAMLOverrides(merged, values)
+		if err != nil || len(changes) != 0 || string(again) != string(merged) {
+			t.Errorf("Expected no changes on second merge, got %+v (%v)", changes, err)
+		}
+	})
+
+	t.Run("Escaped dots stay in the key", func(t *testing.T) {
+		keys := splitDottedPath(`world-settings.my\.world.verbose`)
+		if len(keys) != 3 || keys[1] != "my.world" {
+			t.Errorf("Unexpected keys: %q", keys)
+		}
+	})
+}